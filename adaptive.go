@@ -0,0 +1,155 @@
+package gof2
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// AM is an adaptive matrix of binary elements which tracks its number of
+// nonzero elements and migrates between sparse (LSM) and dense (DM) storage as
+// its density changes. It becomes dense when more than 1/8 of its elements
+// are nonzero and sparse again when fewer than 1/16 are, so that elements
+// toggling near one threshold don't cause repeated migrations.
+type AM struct {
+	// Exactly one of s and d is non-nil.
+	s *LSM
+	d *DM
+	// nnz is the number of nonzero elements while dense.
+	nnz int
+}
+
+const (
+	// amDense and amSparse are the reciprocals of the densities at which AM
+	// migrates to dense and sparse storage, respectively.
+	amDense  = 8
+	amSparse = 16
+)
+
+// NewAdaptive creates a zero matrix of the given size, initially sparse.
+// Panics if either size is non-positive or greater than 4294967295.
+func NewAdaptive(rows, cols int) *AM {
+	return &AM{s: NewLSparse(rows, cols)}
+}
+
+// Adaptive converts any type of binary matrix to a new adaptive matrix, using
+// whichever storage suits its density. Panics if the argument is a polynomial
+// matrix with any element having degree higher than one, or if m is too
+// large.
+func Adaptive(m M) *AM {
+	m = unwrapAdaptive(m)
+	rows, cols := m.Size()
+	if isSparse(m) || !fitsDense(rows, cols) {
+		A := &AM{s: LSparse(m)}
+		A.adapt()
+		return A
+	}
+	A := &AM{d: Dense(m)}
+	for _, w := range A.d.v {
+		A.nnz += bits.OnesCount64(w)
+	}
+	A.adapt()
+	return A
+}
+
+// Size returns the number of rows and columns in the matrix.
+func (A *AM) Size() (rows, cols int) {
+	return A.storage().Size()
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be
+// modified.
+func (A *AM) At(r, c int) *big.Int {
+	return A.storage().At(r, c)
+}
+
+// SetAt sets the value at a one-based row and column index to the given
+// polynomial. Panics if the index is out of bounds or if p is not 0 or 1.
+func (A *AM) SetAt(r, c int, p *big.Int) {
+	if A.s != nil {
+		A.s.SetAt(r, c, p)
+	} else {
+		old := A.d.At(r, c).Sign()
+		A.d.SetAt(r, c, p)
+		A.nnz += p.Sign() - old
+	}
+	A.adapt()
+}
+
+// AddAt adds to the element at the given one-based row and column. Panics if
+// the index is out of bounds or if p is not 0 or 1.
+func (A *AM) AddAt(r, c int, p *big.Int) *big.Int {
+	var v *big.Int
+	if A.s != nil {
+		v = A.s.AddAt(r, c, p)
+	} else {
+		old := A.d.At(r, c).Sign()
+		v = A.d.AddAt(r, c, p)
+		A.nnz += v.Sign() - old
+	}
+	A.adapt()
+	return v
+}
+
+// MulAt multiplies the element at the given one-based row and column. Panics
+// if the index is out of bounds or if p is not 0 or 1.
+func (A *AM) MulAt(r, c int, p *big.Int) *big.Int {
+	var v *big.Int
+	if A.s != nil {
+		v = A.s.MulAt(r, c, p)
+	} else {
+		old := A.d.At(r, c).Sign()
+		v = A.d.MulAt(r, c, p)
+		A.nnz += v.Sign() - old
+	}
+	A.adapt()
+	return v
+}
+
+// IsDense returns whether the matrix currently uses dense storage.
+func (A *AM) IsDense() bool {
+	return A.d != nil
+}
+
+// NNZ returns the number of nonzero elements in the matrix.
+func (A *AM) NNZ() int {
+	if A.s != nil {
+		return len(A.s.v)
+	}
+	return A.nnz
+}
+
+// storage returns the matrix holding the elements.
+func (A *AM) storage() M {
+	if A.s != nil {
+		return A.s
+	}
+	return A.d
+}
+
+// adapt migrates the storage if the density has crossed a threshold.
+func (A *AM) adapt() {
+	rows, cols := A.Size()
+	n := uint64(rows) * uint64(cols)
+	switch {
+	case A.s != nil && uint64(len(A.s.v))*amDense > n && fitsDense(rows, cols):
+		A.d, A.nnz = Dense(A.s), len(A.s.v)
+		A.s = nil
+	case A.d != nil && uint64(A.nnz)*amSparse < n:
+		A.s = LSparse(A.d)
+		A.d = nil
+	}
+}
+
+// fitsDense returns whether a rows x cols matrix can use dense storage.
+func fitsDense(rows, cols int) bool {
+	return uint64(rows) <= maxLarge && uint64(cols) <= maxLarge && rows <= maxInt/cols
+}
+
+// unwrapAdaptive returns the storage of m if it is AM and m otherwise.
+func unwrapAdaptive(m M) M {
+	if A, ok := m.(*AM); ok {
+		return A.storage()
+	}
+	return m
+}
@@ -0,0 +1,123 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestAdaptiveMigration(t *testing.T) {
+	// A 16x16 matrix becomes dense above 32 nonzeros and sparse again below
+	// 16.
+	A := NewAdaptive(16, 16)
+	ref := NewFull(16, 16)
+	one := big.NewInt(1)
+	pos := rand.New(rand.NewSource(1)).Perm(256)
+	set := func(k int, p *big.Int) {
+		A.SetAt(k/16+1, k%16+1, p)
+		ref.SetAt(k/16+1, k%16+1, p)
+	}
+	for n, k := range pos[:40] {
+		set(k, one)
+		if want := n+1 > 32; A.IsDense() != want {
+			t.Fatalf("with %d nonzeros, IsDense is %v", n+1, A.IsDense())
+		}
+		if A.NNZ() != n+1 || !sameElements(A, ref) {
+			t.Fatalf("with %d nonzeros, NNZ is %d or elements differ", n+1, A.NNZ())
+		}
+	}
+	// Removing elements down to the lower threshold keeps dense storage.
+	for n := 40; n > 0; n-- {
+		k := pos[n-1]
+		if n%2 == 0 {
+			A.AddAt(k/16+1, k%16+1, one)
+		} else {
+			A.MulAt(k/16+1, k%16+1, new(big.Int))
+		}
+		ref.SetAt(k/16+1, k%16+1, new(big.Int))
+		if want := n-1 >= 16; A.IsDense() != want {
+			t.Fatalf("with %d nonzeros after shrinking, IsDense is %v", n-1, A.IsDense())
+		}
+		if A.NNZ() != n-1 || !sameElements(A, ref) {
+			t.Fatalf("with %d nonzeros after shrinking, NNZ is %d or elements differ", n-1, A.NNZ())
+		}
+	}
+	// Toggling an element near the upper threshold migrates once each way,
+	// not repeatedly.
+	for _, k := range pos[:33] {
+		set(k, one)
+	}
+	for i := 0; i < 4; i++ {
+		A.AddAt(pos[0]/16+1, pos[0]%16+1, one)
+		if !A.IsDense() {
+			t.Fatalf("toggling near the upper threshold made the matrix sparse")
+		}
+	}
+}
+
+func TestAdaptiveRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	A := NewAdaptive(20, 30)
+	ref := NewFull(20, 30)
+	p := []*big.Int{new(big.Int), big.NewInt(1)}
+	migrations, dense := 0, false
+	for i := 0; i < 12000; i++ {
+		// Drift the density up and down so the matrix migrates many times.
+		row, col, x := r.Intn(20)+1, r.Intn(30)+1, p[1-i/3000%2]
+		switch r.Intn(3) {
+		case 0:
+			A.SetAt(row, col, x)
+			ref.SetAt(row, col, x)
+		case 1:
+			A.AddAt(row, col, x)
+			ref.AddAt(row, col, x)
+		case 2:
+			A.MulAt(row, col, x)
+			ref.MulAt(row, col, x)
+		}
+		if A.IsDense() != dense {
+			dense = A.IsDense()
+			migrations++
+		}
+	}
+	if !sameElements(A, ref) || A.NNZ() != len(Sparse(ref).v) {
+		t.Errorf("elements or NNZ of adaptive matrix disagree after random operations")
+	}
+	if migrations < 2 {
+		t.Errorf("adaptive matrix migrated %d times", migrations)
+	}
+}
+
+func TestAdaptiveOps(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, density := range []int{20, 2} {
+		S := randSparse(r, 40, 70, 40*70/density)
+		F := Full(S)
+		for _, A := range []*AM{Adaptive(S), Adaptive(F), Adaptive(Dense(F))} {
+			if A.IsDense() != (density == 2) {
+				t.Errorf("adaptive matrix with density 1/%d from %T has IsDense %v", density, A.storage(), A.IsDense())
+			}
+			if !sameElements(A, F) || !sameElements(Full(A), F) || !sameElements(Sparse(A), F) || !sameElements(Dense(A), F) || !sameElements(LSparse(A), F) {
+				t.Errorf("conversions of adaptive matrix with density 1/%d disagree", density)
+			}
+			B := randSparse(r, 70, 9, 100)
+			want := fMulNaive(F, B)
+			if !sameElements(FMul(A, B), want) || !sameElements(FMul(A, Full(B)), want) {
+				t.Errorf("products of adaptive matrix with density 1/%d are wrong", density)
+			}
+			C := randSparse(r, 9, 40, 100)
+			if !sameElements(FMul(C, A), fMulNaive(C, F)) || !sameElements(FMul(Full(C), A), fMulNaive(C, F)) {
+				t.Errorf("products by adaptive matrix with density 1/%d are wrong", density)
+			}
+			checkMulVec(t, r, "AM", A)
+		}
+	}
+	// A matrix too large for dense storage stays sparse.
+	L := NewAdaptive(maxLarge, maxLarge)
+	for i := 1; i <= 10; i++ {
+		L.SetAt(i, i, big.NewInt(1))
+	}
+	if L.IsDense() || L.NNZ() != 10 {
+		t.Errorf("huge adaptive matrix has IsDense %v and NNZ %d", L.IsDense(), L.NNZ())
+	}
+}
@@ -0,0 +1,281 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// BM is a banded binary matrix storing only the diagonals from its lower
+// bandwidth below the principal diagonal to its upper bandwidth above it. A
+// diagonal matrix has both bandwidths zero, and a triangular one has either
+// bandwidth zero. BM takes space proportional to the size of the band, so
+// dimensions are limited only by the large matrix limits.
+type BM struct {
+	r, c int
+	// lo and hi are the lower and upper bandwidths.
+	lo, hi int
+	// d holds the diagonals in order from lo below the principal diagonal to
+	// hi above it. Bit i of d[k] holds the element in zero-based row i and
+	// column i+k-lo.
+	d []*big.Int
+}
+
+// NewBanded creates a zero matrix of the given size and bandwidths.
+// Bandwidths larger than the matrix are reduced to fit. Panics if either size
+// is non-positive or too large or if either bandwidth is negative.
+func NewBanded(rows, cols, lower, upper int) *BM {
+	checkLarge(rows, cols, false)
+	if lower < 0 || upper < 0 {
+		panic(fmt.Sprintf("cannot make %dx%d matrix with bandwidths %d, %d: bandwidths must be non-negative", rows, cols, lower, upper))
+	}
+	if lower >= rows {
+		lower = rows - 1
+	}
+	if upper >= cols {
+		upper = cols - 1
+	}
+	d := make([]*big.Int, lower+upper+1)
+	for k := range d {
+		d[k] = new(big.Int)
+	}
+	return &BM{r: rows, c: cols, lo: lower, hi: upper, d: d}
+}
+
+// Banded converts any type of binary matrix to a new banded matrix with the
+// given bandwidths. Only elements within the band are read; all others are
+// treated as zero. Panics if any element in the band is not 0 or 1.
+func Banded(m M, lower, upper int) *BM {
+	m = unwrapAdaptive(m)
+	rows, cols := m.Size()
+	B := NewBanded(rows, cols, lower, upper)
+	for k, d := range B.d {
+		off := k - B.lo
+		for i := B.first(off); i < B.end(off); i++ {
+			if check01(m.At(i+1, i+off+1)) != 0 {
+				d.SetBit(d, i, 1)
+			}
+		}
+	}
+	return B
+}
+
+// Diagonal creates an n x n diagonal matrix with the elements of the vector x
+// on its principal diagonal. Panics if x has bits at or above n.
+func Diagonal(x *big.Int, n int) *BM {
+	if x.Sign() < 0 || x.BitLen() > n {
+		panic(fmt.Sprintf("cannot make %dx%d diagonal matrix from vector of length %d", n, n, x.BitLen()))
+	}
+	B := NewBanded(n, n, 0, 0)
+	B.d[0].Set(x)
+	return B
+}
+
+// Size returns the size of the matrix.
+func (A *BM) Size() (rows, cols int) {
+	return A.r, A.c
+}
+
+// Bandwidth returns the lower and upper bandwidths of the matrix.
+func (A *BM) Bandwidth() (lower, upper int) {
+	return A.lo, A.hi
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be
+// modified.
+func (A *BM) At(r, c int) *big.Int {
+	d, i := A.index(r, c)
+	if d == nil {
+		return zeroP
+	}
+	return to01(d.Bit(i) != 0)
+}
+
+// SetAt sets the element at the given one-based row and column. Panics if the
+// index is out of bounds, if p is not 0 or 1, or if p is 1 and the index is
+// outside the band.
+func (A *BM) SetAt(r, c int, p *big.Int) {
+	d, i := A.index(r, c)
+	b := uint(check01(p))
+	if d == nil {
+		if b != 0 {
+			panic(fmt.Sprintf("cannot set (%d,%d) outside band of %dx%d matrix with bandwidths %d, %d", r, c, A.r, A.c, A.lo, A.hi))
+		}
+		return
+	}
+	d.SetBit(d, i, b)
+}
+
+// AddAt adds to the element at the given one-based row and column. Panics if
+// the index is out of bounds, if p is not 0 or 1, or if p is 1 and the index
+// is outside the band.
+func (A *BM) AddAt(r, c int, p *big.Int) *big.Int {
+	d, i := A.index(r, c)
+	b := uint(check01(p))
+	if d == nil {
+		if b != 0 {
+			panic(fmt.Sprintf("cannot add to (%d,%d) outside band of %dx%d matrix with bandwidths %d, %d", r, c, A.r, A.c, A.lo, A.hi))
+		}
+		return zeroP
+	}
+	d.SetBit(d, i, d.Bit(i)^b)
+	return to01(d.Bit(i) != 0)
+}
+
+// MulAt multiplies the element at the given one-based row and column. Panics
+// if the index is out of bounds or if p is not 0 or 1.
+func (A *BM) MulAt(r, c int, p *big.Int) *big.Int {
+	d, i := A.index(r, c)
+	b := uint(check01(p))
+	if d == nil {
+		return zeroP
+	}
+	d.SetBit(d, i, d.Bit(i)&b)
+	return to01(d.Bit(i) != 0)
+}
+
+// Solve returns the vector x such that A*x = b, where A is a square matrix
+// with either bandwidth zero, by substitution along the band. Returns nil if
+// A is singular, i.e. if any element of its principal diagonal is zero.
+// Panics if A is not square and triangular or if b has bits at or above its
+// size.
+func (A *BM) Solve(b *big.Int) *big.Int {
+	if A.r != A.c || A.lo != 0 && A.hi != 0 {
+		panic(fmt.Sprintf("cannot solve %dx%d matrix with bandwidths %d, %d: matrix must be square and triangular", A.r, A.c, A.lo, A.hi))
+	}
+	n := A.r
+	if b.Sign() < 0 || b.BitLen() > n {
+		panic(fmt.Sprintf("cannot solve %dx%d matrix with vector of length %d", n, n, b.BitLen()))
+	}
+	x := new(big.Int)
+	diag := A.d[A.lo]
+	for s := 0; s < n; s++ {
+		// Back substitution for upper triangular, forward for lower.
+		i := s
+		if A.lo == 0 {
+			i = n - 1 - s
+		}
+		if diag.Bit(i) == 0 {
+			return nil
+		}
+		v := b.Bit(i)
+		for k, d := range A.d {
+			if j := i + k - A.lo; j != i && j >= 0 && j < n {
+				v ^= d.Bit(i) & x.Bit(j)
+			}
+		}
+		x.SetBit(x, i, v)
+	}
+	return x
+}
+
+// first returns the first zero-based row of the diagonal at the given offset
+// above the principal diagonal.
+func (A *BM) first(off int) int {
+	if off < 0 {
+		return -off
+	}
+	return 0
+}
+
+// end returns one past the last zero-based row of the diagonal at the given
+// offset above the principal diagonal.
+func (A *BM) end(off int) int {
+	if A.c-off < A.r {
+		return A.c - off
+	}
+	return A.r
+}
+
+// index panics if the given row or column indices are out of bounds and
+// otherwise returns the diagonal containing the element and the element's
+// position in it, or nil if the element is outside the band.
+func (A *BM) index(r, c int) (*big.Int, int) {
+	if r--; r < 0 || r >= A.r {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r+1, A.r, A.c))
+	}
+	if c--; c < 0 || c >= A.c {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c+1, A.r, A.c))
+	}
+	k := c - r + A.lo
+	if k < 0 || k >= len(A.d) {
+		return nil, 0
+	}
+	return A.d[k], r
+}
+
+// forEach calls f with the zero-based row and column of each nonzero element.
+func (A *BM) forEach(f func(r, c int)) {
+	for k, d := range A.d {
+		off := k - A.lo
+		forBits(d, func(i int) { f(i, i+off) })
+	}
+}
+
+// mulVec returns the product of A by a column vector. Each diagonal is applied
+// to the whole vector at once.
+func (A *BM) mulVec(x *big.Int) *big.Int {
+	y, t := new(big.Int), new(big.Int)
+	for k, d := range A.d {
+		shiftDiag(t, x, k-A.lo)
+		y.Xor(y, t.And(t, d))
+	}
+	return y
+}
+
+// shiftDiag sets z to x shifted so that bit i of z is bit i+off of x.
+func shiftDiag(z, x *big.Int, off int) *big.Int {
+	if off >= 0 {
+		return z.Rsh(x, uint(off))
+	}
+	return z.Lsh(x, uint(-off))
+}
+
+// fMulBand multiplies two banded matrices into a new BM whose bandwidths are
+// the sums of theirs. Each pair of diagonals contributes to one diagonal of
+// the product.
+func fMulBand(A, B *BM) *BM {
+	C := NewBanded(A.r, B.c, A.lo+B.lo, A.hi+B.hi)
+	t := new(big.Int)
+	for ka, da := range A.d {
+		oa := ka - A.lo
+		for kb, db := range B.d {
+			off := oa + kb - B.lo
+			if off < -C.lo || off > C.hi {
+				// Such a diagonal lies outside the product, so every term
+				// in it must be zero.
+				continue
+			}
+			shiftDiag(t, db, oa)
+			d := C.d[off+C.lo]
+			d.Xor(d, t.And(t, da))
+		}
+	}
+	return C
+}
+
+// fMulXBand multiplies a matrix by a banded matrix into a new FM, or LFM if
+// large is true. Each column of the product is the sum of the columns of A
+// selected by the band in the corresponding column of B.
+func fMulXBand(A M, B *BM, large bool) M {
+	src := fullBits(A, large)
+	ar, _ := A.Size()
+	const w = bits.UintSize
+	v := make([]big.Word, ar*B.c/w+1)
+	col := make([]big.Word, ar/w+1)
+	for c := 0; c < B.c; c++ {
+		for i := range col {
+			col[i] = 0
+		}
+		for k, d := range B.d {
+			if r := c - k + B.lo; r >= 0 && r < B.r && d.Bit(r) != 0 {
+				xorBitRange(col, src, r*ar)
+			}
+		}
+		col[ar/w] &= 1<<uint(ar%w) - 1
+		orBitRange(v, col, c*ar)
+	}
+	v[ar*B.c/w] |= 1 << uint(ar*B.c%w)
+	return fullOf(ar, B.c, v, large)
+}
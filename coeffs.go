@@ -0,0 +1,164 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Coeffs splits a polynomial matrix into its coefficients, the binary
+// matrices A_0, ..., A_d such that A = A_0 + A_1 x + ... + A_d x^d, where d is
+// the highest degree of any element of A. The coefficients are SM if A is
+// sparse and FM otherwise, or LSM and LFM if A is a large type or has a
+// dimension over 65535. If A is zero, the result is empty.
+func Coeffs(A M) []M {
+	rows, cols := A.Size()
+	if isLarge(A) || rows > 65535 || cols > 65535 {
+		return lCoeffs(A)
+	}
+	if isSparse(A) {
+		P := PSparse(A)
+		var c []M
+		for k, p := range P.v {
+			for len(c) < p.BitLen() {
+				c = append(c, NewSparse(rows, cols))
+			}
+			forBits(p, func(i int) {
+				c[i].(*SM).v[k] = 1
+			})
+		}
+		return c
+	}
+	// Build the bit vectors of the full matrices directly, with the sentinel
+	// bit past the end of each.
+	n := rows * cols
+	var v [][]big.Word
+	for j := 0; j < cols; j++ {
+		for i := 0; i < rows; i++ {
+			p := A.At(i+1, j+1)
+			for len(v) < p.BitLen() {
+				w := make([]big.Word, n/bits.UintSize+1)
+				w[n/bits.UintSize] = 1 << uint(n%bits.UintSize)
+				v = append(v, w)
+			}
+			b := j*rows + i
+			forBits(p, func(d int) {
+				v[d][b/bits.UintSize] |= 1 << uint(b%bits.UintSize)
+			})
+		}
+	}
+	c := make([]M, len(v))
+	for d, w := range v {
+		c[d] = &FM{uint16(rows), uint16(cols), new(big.Int).SetBits(w)}
+	}
+	return c
+}
+
+// FromCoeffs joins the binary matrices A_0, ..., A_d into the polynomial
+// matrix A_0 + A_1 x + ... + A_d x^d. The result is a PSM if every
+// coefficient is sparse and a PFM otherwise, or LPSM and LPFM if any
+// coefficient is a large type or the size exceeds 65535. Panics if c is empty
+// or if the coefficients are not all the same size.
+func FromCoeffs(c []M) M {
+	if len(c) == 0 {
+		panic("cannot make polynomial matrix from no coefficients")
+	}
+	rows, cols := c[0].Size()
+	sparse, large := true, rows > 65535 || cols > 65535
+	for _, A := range c {
+		if r, s := A.Size(); r != rows || s != cols {
+			panic(fmt.Sprintf("coefficient size mismatch: %dx%d and %dx%d", rows, cols, r, s))
+		}
+		sparse = sparse && isSparse(A)
+		large = large || isLarge(A)
+	}
+	if large {
+		P := NewLPSparse(rows, cols)
+		for d, A := range c {
+			for k, b := range LSparse(A).v {
+				if b == 0 {
+					continue
+				}
+				p := P.v[k]
+				if p == nil {
+					p = new(big.Int)
+					P.v[k] = p
+				}
+				p.SetBit(p, d, 1)
+			}
+		}
+		if sparse {
+			return P
+		}
+		return LPFull(P)
+	}
+	if sparse {
+		P := NewPSparse(rows, cols)
+		for d, A := range c {
+			for k := range Sparse(A).v {
+				p := P.v[k]
+				if p == nil {
+					p = new(big.Int)
+					P.v[k] = p
+				}
+				p.SetBit(p, d, 1)
+			}
+		}
+		return P
+	}
+	P := NewPFull(rows, cols)
+	for d, A := range c {
+		if _, ok := A.(Z); ok {
+			continue
+		}
+		w := Full(A).v
+		forBits(w, func(b int) {
+			// Skip the sentinel bit past the end of the matrix.
+			if b < len(P.v) {
+				P.v[b].SetBit(P.v[b], d, 1)
+			}
+		})
+	}
+	return P
+}
+
+// PTrunc reduces each element of a polynomial matrix modulo x^k, i.e.
+// truncates it as a power series. The result is a new PSM if A is sparse and
+// a new PFM otherwise, or LPSM and LPFM if A is a large type or has a
+// dimension over 65535. Panics if k is negative.
+func PTrunc(A M, k int) M {
+	if k < 0 {
+		panic(fmt.Sprintf("cannot truncate to negative length %d", k))
+	}
+	rows, cols := A.Size()
+	if isLarge(A) || rows > 65535 || cols > 65535 {
+		if isSparse(A) {
+			P := LPSparse(A)
+			for key, p := range P.v {
+				if polyTrunc(p, p, k).Sign() == 0 {
+					delete(P.v, key)
+				}
+			}
+			return P
+		}
+		P := LPFull(A)
+		for _, p := range P.v {
+			polyTrunc(p, p, k)
+		}
+		return P
+	}
+	if isSparse(A) {
+		P := PSparse(A)
+		for key, p := range P.v {
+			if polyTrunc(p, p, k).Sign() == 0 {
+				delete(P.v, key)
+			}
+		}
+		return P
+	}
+	P := PFull(A)
+	for _, p := range P.v {
+		polyTrunc(p, p, k)
+	}
+	return P
+}
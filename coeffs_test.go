@@ -0,0 +1,99 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randPSM creates a rows x cols PSM with about n random elements of degree
+// less than deg.
+func randPSM(r *rand.Rand, rows, cols, n, deg int) *PSM {
+	A := NewPSparse(rows, cols)
+	for i := 0; i < n; i++ {
+		A.SetAt(r.Intn(rows)+1, r.Intn(cols)+1, randPoly(r, r.Intn(deg)))
+	}
+	return A
+}
+
+func TestCoeffs(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sz := range [][2]int{{1, 1}, {3, 5}, {8, 8}, {33, 70}} {
+		for _, A := range []M{randPSM(r, sz[0], sz[1], sz[0], 6), randPFM(r, sz[0], sz[1], 6)} {
+			_, sparse := A.(*PSM)
+			c := Coeffs(A)
+			d := -1
+			for i := 1; i <= sz[0]; i++ {
+				for j := 1; j <= sz[1]; j++ {
+					if e := PolyDeg(A.At(i, j)); e > d {
+						d = e
+					}
+					for k, C := range c {
+						if C.At(i, j).Bit(0) != A.At(i, j).Bit(k) {
+							t.Fatalf("coefficient %d of element (%d,%d) is wrong", k, i, j)
+						}
+					}
+				}
+			}
+			if len(c) != d+1 {
+				t.Errorf("%T of degree %d has %d coefficients", A, d, len(c))
+			}
+			for _, C := range c {
+				if _, ok := C.(*SM); ok != sparse {
+					t.Errorf("coefficient of %T has type %T", A, C)
+				}
+			}
+			if len(c) == 0 {
+				continue
+			}
+			B := FromCoeffs(c)
+			if _, ok := B.(*PSM); ok != sparse {
+				t.Errorf("FromCoeffs of coefficients of %T has type %T", A, B)
+			}
+			if !sameElements(A, B) {
+				t.Errorf("%T does not round-trip through its coefficients", A)
+			}
+		}
+	}
+	if c := Coeffs(NewPSparse(3, 3)); len(c) != 0 {
+		t.Errorf("zero matrix has %d coefficients", len(c))
+	}
+}
+
+func TestFromCoeffsCleared(t *testing.T) {
+	// A cleared element of an SM must not become a non-zero coefficient.
+	A := NewSparse(2, 2)
+	A.SetAt(1, 2, big.NewInt(1))
+	A.SetAt(1, 2, big.NewInt(0))
+	A.SetAt(2, 1, big.NewInt(1))
+	P := FromCoeffs([]M{A, Eye(2, 2)})
+	want := [][]int64{{2, 0}, {1, 2}}
+	for i, row := range want {
+		for j, p := range row {
+			if got := P.At(i+1, j+1); got.Cmp(big.NewInt(p)) != 0 {
+				t.Errorf("element (%d,%d) is %v, want %d", i+1, j+1, got, p)
+			}
+		}
+	}
+}
+
+func TestPTrunc(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, k := range []int{0, 1, 3, 10} {
+		for _, A := range []M{randPSM(r, 7, 9, 20, 8), randPFM(r, 7, 9, 8)} {
+			B := PTrunc(A, k)
+			_, sparse := A.(*PSM)
+			if _, ok := B.(*PSM); ok != sparse {
+				t.Errorf("PTrunc of %T has type %T", A, B)
+			}
+			for i := 1; i <= 7; i++ {
+				for j := 1; j <= 9; j++ {
+					want := polyTrunc(new(big.Int), A.At(i, j), k)
+					if got := B.At(i, j); got.Cmp(want) != 0 {
+						t.Fatalf("element (%d,%d) of %T mod x^%d is %v, want %v", i, j, A, k, got, want)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// CSR is an immutable sparse matrix of binary elements in compressed sparse
+// row form. The column indices of the nonzero elements of each row are stored
+// sorted and contiguous, so rows can be traversed in order without scanning
+// the whole matrix. Dimensions can be up to 4294967295.
+type CSR struct {
+	immutableM
+	// r and c are the size of the matrix.
+	r, c int
+	// s holds the rows.
+	s compressed
+}
+
+// CSC is an immutable sparse matrix of binary elements in compressed sparse
+// column form, storing the sorted row indices of the nonzero elements of each
+// column contiguously. Dimensions can be up to 4294967295.
+type CSC struct {
+	immutableM
+	// r and c are the size of the matrix.
+	r, c int
+	// s holds the columns.
+	s compressed
+}
+
+// compressed is the storage of CSR and CSC. Elements of each line, a row or
+// column respectively, are indexed by the other dimension.
+type compressed struct {
+	// ptr[i] through ptr[i+1] are the indices into idx of line i.
+	ptr []int
+	// idx holds the sorted indices of the nonzero elements of each line.
+	idx []uint32
+}
+
+// CompressRows converts any type of binary matrix to CSR. SM is converted in
+// one pass over its elements; CSR, CSC, and LSM are special-cased as well. All
+// other types are converted through LSparse. Panics if any element is not 0
+// or 1.
+func CompressRows(m M) *CSR {
+	rows, cols := m.Size()
+	switch A := m.(type) {
+	case *CSR:
+		return A
+	case *CSC:
+		return A.CSR()
+	}
+	return &CSR{r: rows, c: cols, s: compressKeys(m, rows, false)}
+}
+
+// CompressCols converts any type of binary matrix to CSC. SM is converted in
+// one pass over its elements; CSR, CSC, and LSM are special-cased as well. All
+// other types are converted through LSparse. Panics if any element is not 0
+// or 1.
+func CompressCols(m M) *CSC {
+	rows, cols := m.Size()
+	switch A := m.(type) {
+	case *CSC:
+		return A
+	case *CSR:
+		return A.CSC()
+	}
+	return &CSC{r: rows, c: cols, s: compressKeys(m, cols, true)}
+}
+
+// compressKeys builds compressed storage with n lines from the nonzero
+// elements of a sparse matrix, by column if byCol is true and by row
+// otherwise.
+func compressKeys(m M, n int, byCol bool) compressed {
+	var major, minor []uint32
+	add := func(r, c uint32) {
+		if byCol {
+			r, c = c, r
+		}
+		major = append(major, r)
+		minor = append(minor, c)
+	}
+	switch A := m.(type) {
+	case *SM:
+		major, minor = make([]uint32, 0, len(A.v)), make([]uint32, 0, len(A.v))
+		for k, v := range A.v {
+			if v != 0 {
+				add(k&0xffff, k>>16)
+			}
+		}
+	case *LSM:
+		major, minor = make([]uint32, 0, len(A.v)), make([]uint32, 0, len(A.v))
+		for k, v := range A.v {
+			if v != 0 {
+				add(uint32(k), uint32(k>>32))
+			}
+		}
+	default:
+		for k := range LSparse(m).v {
+			add(uint32(k), uint32(k>>32))
+		}
+	}
+	// Counting sort the elements by line, then sort within each line.
+	s := compressed{ptr: make([]int, n+1), idx: make([]uint32, len(major))}
+	for _, i := range major {
+		s.ptr[i+1]++
+	}
+	for i := 0; i < n; i++ {
+		s.ptr[i+1] += s.ptr[i]
+	}
+	next := append([]int(nil), s.ptr[:n]...)
+	for k, i := range major {
+		s.idx[next[i]] = minor[k]
+		next[i]++
+	}
+	for i := 0; i < n; i++ {
+		l := s.line(i)
+		sort.Slice(l, func(a, b int) bool { return l[a] < l[b] })
+	}
+	return s
+}
+
+// line returns the sorted indices of line i.
+func (s compressed) line(i int) []uint32 {
+	return s.idx[s.ptr[i]:s.ptr[i+1]:s.ptr[i+1]]
+}
+
+// has returns whether line i has a nonzero element at index j.
+func (s compressed) has(i int, j uint32) bool {
+	l := s.line(i)
+	k := sort.Search(len(l), func(k int) bool { return l[k] >= j })
+	return k < len(l) && l[k] == j
+}
+
+// transpose returns the storage with lines and indices exchanged, where the
+// indices are less than n. The result lines are sorted because the input
+// lines are traversed in order.
+func (s compressed) transpose(n int) compressed {
+	t := compressed{ptr: make([]int, n+1), idx: make([]uint32, len(s.idx))}
+	for _, j := range s.idx {
+		t.ptr[j+1]++
+	}
+	for j := 0; j < n; j++ {
+		t.ptr[j+1] += t.ptr[j]
+	}
+	next := append([]int(nil), t.ptr[:n]...)
+	for i := 0; i+1 < len(s.ptr); i++ {
+		for _, j := range s.line(i) {
+			t.idx[next[j]] = uint32(i)
+			next[j]++
+		}
+	}
+	return t
+}
+
+// mul returns the storage whose line i is the sum of the lines of b selected
+// by line i of a, where the indices of b are less than n. This is Gustavson's
+// algorithm with a dense accumulator.
+func (a compressed) mul(b compressed, n int) compressed {
+	lines := len(a.ptr) - 1
+	c := compressed{ptr: make([]int, lines+1)}
+	acc := make([]bool, n)
+	var touched []uint32
+	for i := 0; i < lines; i++ {
+		touched = touched[:0]
+		for _, k := range a.line(i) {
+			for _, j := range b.line(int(k)) {
+				if !acc[j] {
+					touched = append(touched, j)
+				}
+				acc[j] = !acc[j]
+			}
+		}
+		sort.Slice(touched, func(x, y int) bool { return touched[x] < touched[y] })
+		for _, j := range touched {
+			// Elements toggled an even number of times are zero.
+			if acc[j] {
+				c.idx = append(c.idx, j)
+				acc[j] = false
+			}
+		}
+		c.ptr[i+1] = len(c.idx)
+	}
+	return c
+}
+
+// echelon returns an echelon form of the lines, i.e. a basis of their span in
+// which the lines are sorted by their first index and no two lines have the
+// same first index, followed by as many empty lines as needed to keep the
+// number of lines. It also returns the rank. Lines are reduced as sparse
+// sorted sets, sparsest first to limit fill-in.
+func (s compressed) echelon() (compressed, int) {
+	lines := len(s.ptr) - 1
+	order := make([]int, lines)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(s.line(order[a])) < len(s.line(order[b]))
+	})
+	// pivots maps the first index of each basis line to the line.
+	pivots := make(map[uint32][]uint32)
+	var buf []uint32
+	for _, i := range order {
+		l := append([]uint32(nil), s.line(i)...)
+		for len(l) > 0 {
+			p, ok := pivots[l[0]]
+			if !ok {
+				pivots[l[0]] = l
+				break
+			}
+			buf = symDiff(buf[:0], l, p)
+			l = append(l[:0], buf...)
+		}
+	}
+	firsts := make([]uint32, 0, len(pivots))
+	for j := range pivots {
+		firsts = append(firsts, j)
+	}
+	sort.Slice(firsts, func(a, b int) bool { return firsts[a] < firsts[b] })
+	e := compressed{ptr: make([]int, lines+1)}
+	for i, j := range firsts {
+		e.idx = append(e.idx, pivots[j]...)
+		e.ptr[i+1] = len(e.idx)
+	}
+	for i := len(firsts); i < lines; i++ {
+		e.ptr[i+1] = len(e.idx)
+	}
+	return e, len(firsts)
+}
+
+// symDiff appends to dst the symmetric difference of sorted sets x and y.
+func symDiff(dst, x, y []uint32) []uint32 {
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] < y[j]:
+			dst = append(dst, x[i])
+			i++
+		case x[i] > y[j]:
+			dst = append(dst, y[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	dst = append(dst, x[i:]...)
+	return append(dst, y[j:]...)
+}
+
+// Size returns the number of rows and columns in the matrix.
+func (A *CSR) Size() (rows, cols int) {
+	return A.r, A.c
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be
+// modified.
+func (A *CSR) At(r, c int) *big.Int {
+	checkIndex(r, c, A.r, A.c)
+	return to01(A.s.has(r-1, uint32(c-1)))
+}
+
+// Row returns the one-based column indices of the nonzero elements of the
+// given one-based row in increasing order.
+func (A *CSR) Row(r int) []int {
+	checkIndex(r, 1, A.r, A.c)
+	return lineIndices(A.s.line(r - 1))
+}
+
+// NNZ returns the number of nonzero elements in the matrix.
+func (A *CSR) NNZ() int {
+	return len(A.s.idx)
+}
+
+// Transpose returns the transpose of A.
+func (A *CSR) Transpose() *CSR {
+	return &CSR{r: A.c, c: A.r, s: A.s.transpose(A.c)}
+}
+
+// CSC converts A to compressed sparse column form.
+func (A *CSR) CSC() *CSC {
+	return &CSC{r: A.r, c: A.c, s: A.s.transpose(A.c)}
+}
+
+// Echelon returns a row echelon form of A and its rank. The nonzero rows of
+// the result form a basis of the row space of A, each with its first nonzero
+// element strictly right of that of the row above.
+func (A *CSR) Echelon() (*CSR, int) {
+	e, rank := A.s.echelon()
+	return &CSR{r: A.r, c: A.c, s: e}, rank
+}
+
+// Size returns the number of rows and columns in the matrix.
+func (A *CSC) Size() (rows, cols int) {
+	return A.r, A.c
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be
+// modified.
+func (A *CSC) At(r, c int) *big.Int {
+	checkIndex(r, c, A.r, A.c)
+	return to01(A.s.has(c-1, uint32(r-1)))
+}
+
+// Col returns the one-based row indices of the nonzero elements of the given
+// one-based column in increasing order.
+func (A *CSC) Col(c int) []int {
+	checkIndex(1, c, A.r, A.c)
+	return lineIndices(A.s.line(c - 1))
+}
+
+// NNZ returns the number of nonzero elements in the matrix.
+func (A *CSC) NNZ() int {
+	return len(A.s.idx)
+}
+
+// Transpose returns the transpose of A.
+func (A *CSC) Transpose() *CSC {
+	return &CSC{r: A.c, c: A.r, s: A.s.transpose(A.r)}
+}
+
+// CSR converts A to compressed sparse row form.
+func (A *CSC) CSR() *CSR {
+	return &CSR{r: A.r, c: A.c, s: A.s.transpose(A.r)}
+}
+
+// Echelon returns a column echelon form of A and its rank. The nonzero columns
+// of the result form a basis of the column space of A, each with its first
+// nonzero element strictly below that of the column to its left.
+func (A *CSC) Echelon() (*CSC, int) {
+	e, rank := A.s.echelon()
+	return &CSC{r: A.r, c: A.c, s: e}, rank
+}
+
+// forEach calls f with the zero-based row and column of each nonzero element.
+func (A *CSR) forEach(f func(r, c int)) {
+	for i := 0; i < A.r; i++ {
+		for _, j := range A.s.line(i) {
+			f(i, int(j))
+		}
+	}
+}
+
+// forEach calls f with the zero-based row and column of each nonzero element.
+func (A *CSC) forEach(f func(r, c int)) {
+	for j := 0; j < A.c; j++ {
+		for _, i := range A.s.line(j) {
+			f(int(i), j)
+		}
+	}
+}
+
+// fMulCompressed multiplies two matrices, at least one of which is CSR or CSC.
+// The product is CSC if both are CSC and CSR otherwise.
+func fMulCompressed(A, B M) M {
+	ac, aok := A.(*CSC)
+	bc, bok := B.(*CSC)
+	if aok && bok {
+		// Each column of the product sums the columns of A selected by the
+		// column of B.
+		return &CSC{r: ac.r, c: bc.c, s: bc.s.mul(ac.s, ac.r)}
+	}
+	a, b := CompressRows(A), CompressRows(B)
+	return &CSR{r: a.r, c: b.c, s: a.s.mul(b.s, b.c)}
+}
+
+// lineIndices converts a line of zero-based indices to one-based ints.
+func lineIndices(l []uint32) []int {
+	r := make([]int, len(l))
+	for i, j := range l {
+		r[i] = int(j) + 1
+	}
+	return r
+}
+
+// checkIndex panics if a one-based index is out of bounds of a rows x cols
+// matrix.
+func checkIndex(r, c, rows, cols int) {
+	if r <= 0 || r > rows {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r, rows, cols))
+	}
+	if c <= 0 || c > cols {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c, rows, cols))
+	}
+}
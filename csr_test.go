@@ -0,0 +1,157 @@
+package gof2
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCompressed(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sz := range [][2]int{{1, 1}, {5, 9}, {9, 5}, {70, 130}} {
+		S := randSparse(r, sz[0], sz[1], sz[0]*sz[1]/5)
+		F := Full(S)
+		A, B := CompressRows(S), CompressCols(S)
+		for _, X := range []M{A, B, CompressRows(F), CompressCols(F), B.CSR(), A.CSC(), CompressRows(B), CompressCols(A)} {
+			if !sameElements(X, F) {
+				t.Errorf("%T of size %dx%d disagrees with its source", X, sz[0], sz[1])
+			}
+			if !sameElements(Full(X), F) || !sameElements(Sparse(X), F) || !sameElements(Dense(X), F) || !sameElements(LSparse(X), F) {
+				t.Errorf("conversions of %T of size %dx%d disagree", X, sz[0], sz[1])
+			}
+			checkMulVec(t, r, "compressed", X)
+		}
+		nnz := len(Sparse(F).v)
+		if A.NNZ() != nnz || B.NNZ() != nnz {
+			t.Errorf("%dx%d matrix with %d nonzeros has NNZ %d and %d", sz[0], sz[1], nnz, A.NNZ(), B.NNZ())
+		}
+		for i := 1; i <= sz[0]; i++ {
+			last := 0
+			for _, j := range A.Row(i) {
+				if j <= last || F.At(i, j).Sign() == 0 {
+					t.Fatalf("row %d of CSR lists column %d wrongly", i, j)
+				}
+				last = j
+			}
+		}
+		for j := 1; j <= sz[1]; j++ {
+			last := 0
+			for _, i := range B.Col(j) {
+				if i <= last || F.At(i, j).Sign() == 0 {
+					t.Fatalf("column %d of CSC lists row %d wrongly", j, i)
+				}
+				last = i
+			}
+		}
+		transposed := func(i, j int) (int, int, bool) { return j, i, true }
+		checkMap(t, A.Transpose(), F, transposed)
+		checkMap(t, B.Transpose(), F, transposed)
+		if !sameElements(A.Transpose().Transpose(), F) || !sameElements(B.Transpose().Transpose(), F) {
+			t.Errorf("double transpose of %dx%d matrix disagrees", sz[0], sz[1])
+		}
+	}
+}
+
+func TestCompressedMul(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, sz := range [][3]int{{1, 1, 1}, {5, 9, 3}, {70, 130, 65}} {
+		S, U := randSparse(r, sz[0], sz[1], sz[0]*sz[1]/5), randSparse(r, sz[1], sz[2], sz[1]*sz[2]/5)
+		want := fMulNaive(S, U)
+		as := []M{CompressRows(S), CompressCols(S), S, Full(S)}
+		bs := []M{CompressRows(U), CompressCols(U), U, Full(U)}
+		for i, X := range as {
+			for j, Y := range bs {
+				if i >= 2 && j >= 2 {
+					continue
+				}
+				C := FMul(X, Y)
+				if !sameElements(C, want) {
+					t.Errorf("%T * %T of size %dx%dx%d is wrong", X, Y, sz[0], sz[1], sz[2])
+				}
+				_, xc := X.(*CSC)
+				_, yc := Y.(*CSC)
+				if _, ok := C.(*CSC); ok != (xc && yc) {
+					t.Errorf("%T * %T has type %T", X, Y, C)
+				}
+			}
+		}
+	}
+}
+
+func TestCompressedEchelon(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, sz := range [][2]int{{1, 1}, {5, 9}, {9, 5}, {40, 70}, {70, 40}} {
+		S := randSparse(r, sz[0], sz[1], sz[0]*sz[1]/4)
+		if sz[0] > 1 {
+			for j := 1; j <= sz[1]; j++ {
+				S.SetAt(sz[0], j, S.At(1, j))
+			}
+		}
+		want := Dense(S).Eliminate()
+		E, rank := CompressRows(S).Echelon()
+		if rank != want {
+			t.Errorf("rank of %dx%d CSR is %d, want %d", sz[0], sz[1], rank, want)
+		}
+		// The echelon form spans the same rows, so stacking it under the
+		// original does not increase the rank.
+		checkEchelon(t, E, rank)
+		stack := NewDense(2*sz[0], sz[1])
+		for i := 1; i <= sz[0]; i++ {
+			for j := 1; j <= sz[1]; j++ {
+				stack.SetAt(i, j, S.At(i, j))
+				stack.SetAt(i+sz[0], j, E.At(i, j))
+			}
+		}
+		if got := stack.Eliminate(); got != want {
+			t.Errorf("echelon form of %dx%d CSR spans a different space", sz[0], sz[1])
+		}
+		// The column echelon form of the CSC is that of the transpose.
+		C, crank := CompressCols(S).Echelon()
+		if crank != want {
+			t.Errorf("rank of %dx%d CSC is %d, want %d", sz[0], sz[1], crank, want)
+		}
+		checkEchelon(t, C.Transpose().CSR(), crank)
+	}
+}
+
+// checkEchelon checks that the rows of E have strictly increasing first
+// columns, with rank nonzero rows.
+func checkEchelon(t *testing.T, E *CSR, rank int) {
+	t.Helper()
+	rows, _ := E.Size()
+	last := 0
+	for i := 1; i <= rows; i++ {
+		row := E.Row(i)
+		if (len(row) == 0) != (i > rank) {
+			t.Fatalf("row %d of echelon form of rank %d has %d elements", i, rank, len(row))
+		}
+		if len(row) > 0 {
+			if row[0] <= last {
+				t.Fatalf("row %d of echelon form starts at column %d, after %d", i, row[0], last)
+			}
+			last = row[0]
+		}
+	}
+}
+
+func TestCompressedLarge(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	L := largeSparse(r, largeN, 3)
+	A, B := CompressRows(L), CompressCols(L)
+	for k, v := range L.v {
+		if v == 0 {
+			continue
+		}
+		i, j := int(k&0xffffffff)+1, int(k>>32)+1
+		if A.At(i, j).Sign() == 0 || B.At(i, j).Sign() == 0 {
+			t.Errorf("element (%d,%d) is missing from compressed forms", i, j)
+		}
+	}
+	if A.NNZ() != len(L.v) || B.NNZ() != len(L.v) {
+		t.Errorf("compressed forms have %d and %d nonzeros, want %d", A.NNZ(), B.NNZ(), len(L.v))
+	}
+	C := FMul(A.Transpose(), B)
+	D := FMul(LSparse(A.Transpose()), L)
+	if !sameElements(C, D) {
+		t.Errorf("product of %dx3 compressed matrix with its transpose is wrong", largeN)
+	}
+}
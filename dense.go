@@ -0,0 +1,322 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// DM is a dense matrix of binary elements stored row-major, with each row
+// packed into whole 64-bit words in the manner of M4RI. Unlike FM, row
+// operations act on 64 elements per word, and setting elements never
+// reallocates. Dimensions are limited only by memory.
+type DM struct {
+	r, c int
+	// stride is the number of words per row.
+	stride int
+	// v holds the rows in order. Bit j of word k of a row holds the element in
+	// column 64k+j. Bits past the last column are always zero.
+	v []uint64
+}
+
+// NewDense creates a zero matrix of the given size. Panics if either size is
+// non-positive or if the matrix has too many elements.
+func NewDense(rows, cols int) *DM {
+	checkLarge(rows, cols, true)
+	stride := (cols + 63) / 64
+	return &DM{r: rows, c: cols, stride: stride, v: make([]uint64, rows*stride)}
+}
+
+// Dense converts any type of binary matrix to a new dense matrix. Panics if the
+// argument is a polynomial matrix with any element having degree higher than
+// one. Types DM, SM, FM, LSM, LFM, CSR, CSC, BM, TM, Toep, Circ, I, Z, R, S,
+// Comp, and Perm are special-cased, Sum and Product are evaluated first, and V
+// is read from its viewed matrix. All other types are filled in O(mn) time.
+func Dense(m M) *DM {
+	m = unwrapAdaptive(m)
+	rows, cols := m.Size()
+	B := NewDense(rows, cols)
+	switch A := m.(type) {
+	case *DM:
+		copy(B.v, A.v)
+	case *SM:
+		for k, v := range A.v {
+			if v != 0 {
+				B.set(int(k&0xffff), int(k>>16))
+			}
+		}
+	case *LSM:
+		for k, v := range A.v {
+			if v != 0 {
+				B.set(int(k&0xffffffff), int(k>>32))
+			}
+		}
+	case *FM:
+		forMatrixBits(A.v, rows, cols, B.set)
+	case *LFM:
+		forMatrixBits(A.v, rows, cols, B.set)
+	case *CSR:
+		A.forEach(B.set)
+	case *CSC:
+		A.forEach(B.set)
+	case Comp:
+		A.forEach(B.set)
+	case Perm:
+		A.forEach(B.set)
+	case *BM:
+		A.forEach(B.set)
+	case *TM:
+		return A.dense()
+	case Toep:
+		A.forEach(B.set)
+	case Circ:
+		A.forEach(B.set)
+	case Sum:
+		return Dense(A.Eval())
+	case Product:
+		return Dense(A.Eval())
+	case V:
+		return Dense(A.binary())
+	case I:
+		for k := 0; k < rows && k < cols; k++ {
+			B.set(k, k)
+		}
+	case Z:
+		// do nothing
+	case R:
+		for i := 0; i < rows; i++ {
+			r := (i + A.n) % rows
+			if r < 0 {
+				r += rows
+			}
+			B.set(r, i)
+		}
+	case S:
+		for i := 0; i < rows; i++ {
+			if r := i + A.n; r >= 0 && r < rows {
+				B.set(r, i)
+			}
+		}
+	default:
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				if check01(A.At(r+1, c+1)) != 0 {
+					B.set(r, c)
+				}
+			}
+		}
+	}
+	return B
+}
+
+// Size returns the number of rows and columns in the matrix.
+func (dm *DM) Size() (rows, cols int) {
+	return dm.r, dm.c
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be
+// modified.
+func (dm *DM) At(r, c int) *big.Int {
+	k, b := dm.index(r, c)
+	return to01(dm.v[k]&b != 0)
+}
+
+// SetAt sets the value at a one-based row and column index to the given
+// polynomial. Panics if the index is out of bounds or if p is not 0 or 1.
+func (dm *DM) SetAt(r, c int, p *big.Int) {
+	k, b := dm.index(r, c)
+	if check01(p) != 0 {
+		dm.v[k] |= b
+	} else {
+		dm.v[k] &^= b
+	}
+}
+
+// AddAt adds to the element at the given one-based row and column. Panics if
+// the index is out of bounds or if p is not 0 or 1.
+func (dm *DM) AddAt(r, c int, p *big.Int) *big.Int {
+	k, b := dm.index(r, c)
+	if check01(p) != 0 {
+		dm.v[k] ^= b
+	}
+	return to01(dm.v[k]&b != 0)
+}
+
+// MulAt multiplies the element at the given one-based row and column. Panics
+// if the index is out of bounds or if p is not 0 or 1.
+func (dm *DM) MulAt(r, c int, p *big.Int) *big.Int {
+	k, b := dm.index(r, c)
+	if check01(p) == 0 {
+		dm.v[k] &^= b
+	}
+	return to01(dm.v[k]&b != 0)
+}
+
+// AddRow adds the row at one-based index src into the row at dst. Panics if
+// either index is out of bounds.
+func (dm *DM) AddRow(dst, src int) {
+	xorRow(dm.row(dst-1), dm.row(src-1))
+}
+
+// SwapRows exchanges the rows at one-based indices i and j. Panics if either
+// index is out of bounds.
+func (dm *DM) SwapRows(i, j int) {
+	x, y := dm.row(i-1), dm.row(j-1)
+	for k := range x {
+		x[k], y[k] = y[k], x[k]
+	}
+}
+
+// Eliminate transforms the matrix in place into reduced row echelon form using
+// Gauss-Jordan elimination and returns its rank.
+func (dm *DM) Eliminate() int {
+	r := 0
+	for c := 0; c < dm.c && r < dm.r; c++ {
+		k, b := c/64, uint64(1)<<uint(c%64)
+		p := r
+		for p < dm.r && dm.v[p*dm.stride+k]&b == 0 {
+			p++
+		}
+		if p == dm.r {
+			continue
+		}
+		if p != r {
+			dm.SwapRows(p+1, r+1)
+		}
+		pr := dm.row(r)
+		for i := 0; i < dm.r; i++ {
+			if i != r && dm.v[i*dm.stride+k]&b != 0 {
+				// Words left of the pivot are zero in the pivot row.
+				xorRow(dm.row(i)[k:], pr[k:])
+			}
+		}
+		r++
+	}
+	return r
+}
+
+// row returns the words of the row at a zero-based index.
+func (dm *DM) row(r int) []uint64 {
+	if r < 0 || r >= dm.r {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r+1, dm.r, dm.c))
+	}
+	return dm.v[r*dm.stride : (r+1)*dm.stride : (r+1)*dm.stride]
+}
+
+// set sets the element at a zero-based row and column without bounds checks.
+func (dm *DM) set(r, c int) {
+	dm.v[r*dm.stride+c/64] |= 1 << uint(c%64)
+}
+
+// index panics if the given row or column indices are out of bounds and
+// returns the corresponding word index and bit mask otherwise.
+func (dm *DM) index(r, c int) (int, uint64) {
+	if r--; r < 0 || r >= dm.r {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r+1, dm.r, dm.c))
+	}
+	if c--; c < 0 || c >= dm.c {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c+1, dm.r, dm.c))
+	}
+	return r*dm.stride + c/64, 1 << uint(c%64)
+}
+
+// xorRow adds src into dst.
+func xorRow(dst, src []uint64) {
+	for i, w := range src {
+		dst[i] ^= w
+	}
+}
+
+// fMulDense multiplies two dense matrices into a new DM using the Method of
+// Four Russians: for each group of eight rows of B, all 256 sums of those rows
+// are tabulated, and each row of A selects one with a byte.
+func fMulDense(A, B *DM) *DM {
+	C := NewDense(A.r, B.c)
+	var tab [256][]uint64
+	buf := make([]uint64, 256*B.stride)
+	for i := range tab {
+		tab[i] = buf[i*B.stride : (i+1)*B.stride]
+	}
+	for g := 0; g < A.c; g += 8 {
+		n := A.c - g
+		if n > 8 {
+			n = 8
+		}
+		// Gray code order makes each table entry one row addition away from
+		// the previous one.
+		for i := range tab[0] {
+			tab[0][i] = 0
+		}
+		prev := 0
+		for i := 1; i < 1<<uint(n); i++ {
+			gray := i ^ i>>1
+			bit := bits.TrailingZeros(uint(gray ^ prev))
+			copy(tab[gray], tab[prev])
+			xorRow(tab[gray], B.row(g+bit))
+			prev = gray
+		}
+		k, s := g/64, uint(g%64)
+		for r := 0; r < A.r; r++ {
+			// The group never straddles words since 8 divides 64.
+			sel := A.v[r*A.stride+k] >> s & (1<<uint(n) - 1)
+			if sel != 0 {
+				xorRow(C.row(r), tab[sel])
+			}
+		}
+	}
+	return C
+}
+
+// forEach calls f with the zero-based row and column of each set element in
+// row-major order.
+func (dm *DM) forEach(f func(r, c int)) {
+	for i, w := range dm.v {
+		r, k := i/dm.stride, i%dm.stride
+		for w != 0 {
+			f(r, k*64+bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+}
+
+// words64 returns the low n bits of x as 64-bit words.
+func words64(x *big.Int, n int) []uint64 {
+	v := make([]uint64, (n+63)/64)
+	for i, w := range x.Bits() {
+		k := i * bits.UintSize
+		if k >= len(v)*64 {
+			break
+		}
+		v[k/64] |= uint64(w) << uint(k%64)
+	}
+	if n%64 != 0 {
+		v[len(v)-1] &= 1<<uint(n%64) - 1
+	}
+	return v
+}
+
+// denseBits returns the words of the column-major bit vector of a full matrix
+// with the same elements as dm, including the sentinel bit.
+func denseBits(dm *DM) []big.Word {
+	n := dm.r * dm.c
+	v := make([]big.Word, n/bits.UintSize+1)
+	dm.forEach(func(r, c int) {
+		b := c*dm.r + r
+		v[b/bits.UintSize] |= 1 << uint(b%bits.UintSize)
+	})
+	v[n/bits.UintSize] |= 1 << uint(n%bits.UintSize)
+	return v
+}
+
+// bigWords64 returns the integer whose 64-bit words are v, the inverse of
+// words64.
+func bigWords64(v []uint64) *big.Int {
+	w := make([]big.Word, (len(v)*64+bits.UintSize-1)/bits.UintSize)
+	for i, x := range v {
+		for k := 0; k < 64; k += bits.UintSize {
+			w[(i*64+k)/bits.UintSize] = big.Word(x >> uint(k))
+		}
+	}
+	return new(big.Int).SetBits(w)
+}
@@ -0,0 +1,173 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// fMulNaive multiplies two binary matrices into a new FM using At. This was
+// the kernel of fMulFull before DM.
+func fMulNaive(A, B M) *FM {
+	ar, ac := A.Size()
+	_, bc := B.Size()
+	C := NewFull(ar, bc)
+	for c := 1; c <= bc; c++ {
+		for r := 1; r <= ar; r++ {
+			var d uint8
+			for i := 1; i <= ac; i++ {
+				d ^= check01(A.At(r, i)) & check01(B.At(i, c))
+			}
+			C.SetAt(r, c, to01(d != 0))
+		}
+	}
+	return C
+}
+
+// randDense creates a rows x cols DM with random elements.
+func randDense(r *rand.Rand, rows, cols int) *DM {
+	A := NewDense(rows, cols)
+	for i := range A.v {
+		A.v[i] = r.Uint64()
+	}
+	if cols%64 != 0 {
+		for i := 0; i < rows; i++ {
+			A.v[(i+1)*A.stride-1] &= 1<<uint(cols%64) - 1
+		}
+	}
+	return A
+}
+
+func TestDenseConvert(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sz := range [][2]int{{1, 1}, {3, 64}, {65, 7}, {70, 130}} {
+		A := randDense(r, sz[0], sz[1])
+		F := fMulNaive(A, Eye(sz[1], sz[1]))
+		if !sameElements(Full(A), F) || !sameElements(Sparse(A), F) || !sameElements(LSparse(A), F) || !sameElements(LFull(A), F) {
+			t.Errorf("conversions of %dx%d DM disagree", sz[0], sz[1])
+		}
+		for _, X := range []M{F, Sparse(F), LSparse(F), LFull(F), A} {
+			if !sameElements(Dense(X), F) {
+				t.Errorf("Dense of %T of size %dx%d disagrees", X, sz[0], sz[1])
+			}
+		}
+		checkMulVec(t, r, "DM", A)
+	}
+}
+
+func TestDenseMul(t *testing.T) {
+	// Column counts that are not multiples of 8 or 64 leave partial groups
+	// in the Four Russians tables and partial words in the rows.
+	r := rand.New(rand.NewSource(2))
+	dims := []int{1, 5, 8, 13, 64, 67, 130}
+	for _, m := range dims {
+		for _, k := range dims {
+			for _, n := range []int{1, 9, 65} {
+				A, B := randDense(r, m, k), randDense(r, k, n)
+				want := fMulNaive(A, B)
+				if C := FMul(A, B); !sameElements(C, want) {
+					t.Errorf("DM product of sizes %dx%d and %dx%d is wrong", m, k, k, n)
+				}
+				if C := FMul(Full(A), Full(B)); !sameElements(C, want) {
+					t.Errorf("FM product of sizes %dx%d and %dx%d is wrong", m, k, k, n)
+				}
+				if C := FMul(A, Sparse(B)); !sameElements(C, want) {
+					t.Errorf("DM*SM product of sizes %dx%d and %dx%d is wrong", m, k, k, n)
+				}
+			}
+		}
+	}
+}
+
+func TestDenseEliminate(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, sz := range [][2]int{{1, 1}, {5, 9}, {9, 5}, {40, 70}, {70, 40}, {64, 64}} {
+		A := randDense(r, sz[0], sz[1])
+		if sz[0] > 1 {
+			// Reduce the rank.
+			copy(A.row(sz[0]-1), A.row(0))
+		}
+		E := Dense(A)
+		rank := E.Eliminate()
+		inv, _, _ := Smith(A, false)
+		if rank != len(inv) {
+			t.Errorf("rank of %dx%d matrix is %d, want %d", sz[0], sz[1], rank, len(inv))
+		}
+		// Check reduced row echelon form and record the pivots.
+		var pivots []int
+		for i := 0; i < sz[0]; i++ {
+			c := 0
+			for c < sz[1] && E.At(i+1, c+1).Sign() == 0 {
+				c++
+			}
+			if c == sz[1] {
+				if i < rank {
+					t.Fatalf("row %d of %dx%d echelon form is zero but rank is %d", i+1, sz[0], sz[1], rank)
+				}
+				continue
+			}
+			if i >= rank || len(pivots) > 0 && c <= pivots[len(pivots)-1] {
+				t.Fatalf("row %d of %dx%d echelon form has pivot in column %d", i+1, sz[0], sz[1], c+1)
+			}
+			for j := 0; j < sz[0]; j++ {
+				if j != i && E.At(j+1, c+1).Sign() != 0 {
+					t.Fatalf("pivot column %d of %dx%d echelon form is not reduced", c+1, sz[0], sz[1])
+				}
+			}
+			pivots = append(pivots, c)
+		}
+		// Each row of A is in the row space of E.
+		for i := 0; i < sz[0]; i++ {
+			x := append([]uint64(nil), A.row(i)...)
+			for j, c := range pivots {
+				if x[c/64]&(1<<uint(c%64)) != 0 {
+					xorRow(x, E.row(j))
+				}
+			}
+			for _, w := range x {
+				if w != 0 {
+					t.Fatalf("row %d of %dx%d matrix is not in the span of its echelon form", i+1, sz[0], sz[1])
+				}
+			}
+		}
+	}
+}
+
+func TestDenseRowOps(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	A := randDense(r, 4, 100)
+	B := Dense(A)
+	B.AddRow(1, 3)
+	B.SwapRows(2, 4)
+	for c := 1; c <= 100; c++ {
+		want := []uint{A.At(1, c).Bit(0) ^ A.At(3, c).Bit(0), A.At(4, c).Bit(0), A.At(3, c).Bit(0), A.At(2, c).Bit(0)}
+		for i, w := range want {
+			if B.At(i+1, c).Bit(0) != w {
+				t.Fatalf("element (%d,%d) after row operations is wrong", i+1, c)
+			}
+		}
+	}
+	B.SetAt(2, 100, big.NewInt(1))
+	B.AddAt(2, 100, big.NewInt(1))
+	if B.At(2, 100).Sign() != 0 {
+		t.Errorf("SetAt then AddAt of 1 leaves %v", B.At(2, 100))
+	}
+}
+
+func BenchmarkFMulFull(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{4, 16, 64, 256} {
+		A, B := Full(randDense(r, n, n)), Full(randDense(r, n, n))
+		b.Run(fmt.Sprintf("At/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fMulNaive(A, B)
+			}
+		})
+		b.Run(fmt.Sprintf("Dense/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fMulFull(A, B)
+			}
+		})
+	}
+}
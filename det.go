@@ -0,0 +1,102 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// CharMatrix creates the characteristic matrix xI - A of a square binary
+// matrix A as a PSM. Its determinant is the characteristic polynomial of A.
+// Panics if A is not square or if it has any element other than 0 or 1.
+func CharMatrix(A M) *PSM {
+	n, c := A.Size()
+	if n != c {
+		panic(fmt.Sprintf("cannot make characteristic matrix of %dx%d matrix: matrix must be square", n, c))
+	}
+	B := PSparse(Sparse(A))
+	for k := 0; k < n; k++ {
+		i := uint32(k) * 0x00010001
+		if B.v[i] == nil {
+			B.v[i] = new(big.Int)
+		}
+		// Over GF(2), -A = A and adding x flips the bit of x^1.
+		B.v[i].SetBit(B.v[i], 1, 1)
+	}
+	return B
+}
+
+// Det computes the determinant of a square matrix over GF(2)[x] using
+// Bareiss's fraction-free elimination, which keeps every intermediate element
+// a polynomial of degree at most the degree of the final minor it represents.
+// Det(CharMatrix(A)) gives the characteristic polynomial of A independently of
+// any sequence-based method. Panics if A is not square.
+func Det(A M) *big.Int {
+	n, c := A.Size()
+	if n != c {
+		panic(fmt.Sprintf("cannot take determinant of %dx%d matrix: matrix must be square", n, c))
+	}
+	a := denseRows(A)
+	prev := big.NewInt(1)
+	var t, u big.Int
+	for k := 0; k < n-1; k++ {
+		// Row swaps would negate the determinant, but -1 = 1 in GF(2).
+		p := k
+		for p < n && a[p][k].Sign() == 0 {
+			p++
+		}
+		if p == n {
+			return new(big.Int)
+		}
+		a[k], a[p] = a[p], a[k]
+		for i := k + 1; i < n; i++ {
+			for j := k + 1; j < n; j++ {
+				PolyMul(&t, a[i][j], a[k][k])
+				PolyMul(&u, a[i][k], a[k][j])
+				t.Xor(&t, &u)
+				// The division is exact by Sylvester's identity.
+				PolyDivMod(a[i][j], nil, &t, prev)
+			}
+		}
+		prev = a[k][k]
+	}
+	return new(big.Int).Set(a[n-1][n-1])
+}
+
+// denseRows copies the elements of A into a slice of rows of new polynomials.
+func denseRows(A M) [][]*big.Int {
+	rows, cols := A.Size()
+	a := make([][]*big.Int, rows)
+	for i := range a {
+		a[i] = make([]*big.Int, cols)
+		for j := range a[i] {
+			a[i][j] = new(big.Int).Set(A.At(i+1, j+1))
+		}
+	}
+	return a
+}
+
+// pfmRows creates a PFM from a slice of rows of polynomials. The polynomials
+// are not copied.
+func pfmRows(a [][]*big.Int) *PFM {
+	rows, cols := len(a), len(a[0])
+	B := PFM{uint16(rows), uint16(cols), make([]*big.Int, rows*cols)}
+	for r, row := range a {
+		for c, p := range row {
+			B.v[c*rows+r] = p
+		}
+	}
+	return &B
+}
+
+// eyeRows creates the n x n identity as a slice of rows of polynomials.
+func eyeRows(n int) [][]*big.Int {
+	a := make([][]*big.Int, n)
+	for i := range a {
+		a[i] = make([]*big.Int, n)
+		for j := range a[i] {
+			a[i][j] = new(big.Int)
+		}
+		a[i][i].SetInt64(1)
+	}
+	return a
+}
@@ -0,0 +1,103 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randPFM creates a rows x cols PFM with random elements of degree less than
+// deg.
+func randPFM(r *rand.Rand, rows, cols, deg int) *PFM {
+	A := NewPFull(rows, cols)
+	for i := 1; i <= rows; i++ {
+		for j := 1; j <= cols; j++ {
+			A.SetAt(i, j, randPoly(r, r.Intn(deg+1)-1))
+		}
+	}
+	return A
+}
+
+// detNaive computes the determinant of a square matrix over GF(2)[x] by
+// cofactor expansion along the first row.
+func detNaive(a [][]*big.Int) *big.Int {
+	n := len(a)
+	if n == 0 {
+		return big.NewInt(1)
+	}
+	d := new(big.Int)
+	var t big.Int
+	for j := 0; j < n; j++ {
+		if a[0][j].Sign() == 0 {
+			continue
+		}
+		minor := make([][]*big.Int, n-1)
+		for i := range minor {
+			minor[i] = append(append([]*big.Int{}, a[i+1][:j]...), a[i+1][j+1:]...)
+		}
+		d.Xor(d, PolyMul(&t, a[0][j], detNaive(minor)))
+	}
+	return d
+}
+
+func TestDet(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 1; n <= 6; n++ {
+		for i := 0; i < 10; i++ {
+			A := randPFM(r, n, n, 4)
+			want := detNaive(denseRows(A))
+			if got := Det(A); got.Cmp(want) != 0 {
+				t.Errorf("Det of %dx%d matrix is %v, want %v", n, n, got, want)
+			}
+		}
+	}
+}
+
+func TestDetProduct(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for n := 1; n <= 12; n++ {
+		A, B := randPFM(r, n, n, 3), randPFM(r, n, n, 3)
+		want := PolyMul(new(big.Int), Det(A), Det(B))
+		if got := Det(PMul(A, B)); got.Cmp(want) != 0 {
+			t.Errorf("det(AB) of %dx%d matrices is %v, want %v", n, n, got, want)
+		}
+		if n > 1 {
+			// Repeating a row makes the matrix singular.
+			for j := 1; j <= n; j++ {
+				A.SetAt(n, j, A.At(1, j))
+			}
+			if d := Det(A); d.Sign() != 0 {
+				t.Errorf("Det of %dx%d matrix with repeated row is %v", n, n, d)
+			}
+		}
+	}
+}
+
+func TestDetCharMatrix(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, n := range []int{1, 2, 5, 16, 40} {
+		f := randPoly(r, n)
+		f.SetBit(f, 0, 1)
+		if got := Det(CharMatrix(Companion(f))); got.Cmp(f) != 0 {
+			t.Errorf("characteristic polynomial of companion of %v is %v", f, got)
+		}
+	}
+	// The characteristic polynomial of a block diagonal matrix is the
+	// product of those of its blocks.
+	f, g := big.NewInt(0x13), big.NewInt(0x7)
+	A := NewSparse(6, 6)
+	F, G := Companion(f), Companion(g)
+	for i := 1; i <= 4; i++ {
+		for j := 1; j <= 4; j++ {
+			A.SetAt(i, j, F.At(i, j))
+		}
+	}
+	for i := 1; i <= 2; i++ {
+		for j := 1; j <= 2; j++ {
+			A.SetAt(i+4, j+4, G.At(i, j))
+		}
+	}
+	if got, want := Det(CharMatrix(A)), PolyMul(new(big.Int), f, g); got.Cmp(want) != 0 {
+		t.Errorf("characteristic polynomial of block diagonal matrix is %v, want %v", got, want)
+	}
+}
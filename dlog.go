@@ -0,0 +1,277 @@
+package gof2
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+)
+
+// ErrNoLog is returned by DiscreteLog and Distance when no solution exists,
+// i.e. when the target is not in the subgroup generated by the base.
+var ErrNoLog = errors.New("no discrete logarithm exists")
+
+// ErrRhoFailed is returned by DiscreteLog and Distance when Pollard's rho
+// method gives up on a prime-order subgroup too large for baby-step
+// giant-step. Rho is probabilistic, so a logarithm may still exist.
+var ErrRhoFailed = errors.New("discrete logarithm not found by Pollard's rho within its step limit")
+
+// FactorTooLargeError is returned by DiscreteLog and Distance when the order
+// of the base has a prime factor too large to take logarithms in its
+// subgroup, or when 2^n - 1 has a composite factor whose prime factors are
+// too large to find.
+type FactorTooLargeError struct {
+	// Factor is the offending prime factor, or the composite factor that
+	// could not be split.
+	Factor *big.Int
+	// Composite is whether Factor is composite.
+	Composite bool
+}
+
+func (err *FactorTooLargeError) Error() string {
+	if err.Composite {
+		return fmt.Sprintf("could not factor %v: factors are too large for Pollard's rho", err.Factor)
+	}
+	return fmt.Sprintf("prime factor %v of group order is too large for discrete logarithm (limit 2^%d)", err.Factor, rhoMaxBits)
+}
+
+const (
+	// bsgsMaxBits is the size in bits of the largest prime subgroup order
+	// handled by baby-step giant-step. The table has 2^(bsgsMaxBits/2)
+	// entries.
+	bsgsMaxBits = 36
+	// rhoMaxBits is the size in bits of the largest prime subgroup order
+	// handled at all, using Pollard's rho method above bsgsMaxBits. Rho takes
+	// about 2^(rhoMaxBits/2) multiplications in the worst case.
+	rhoMaxBits = 56
+)
+
+// Distance returns the number of steps from one state to another of a linear
+// generator with irreducible characteristic polynomial f, where states are
+// expressed as polynomials in GF(2)[x]/(f). That is, it finds the least k >= 0
+// such that to = from*x^k mod f. If f is primitive, the states on the cycle
+// are all non-zero polynomials of degree less than that of f.
+//
+// Distance returns ErrNoLog if to is not reachable from from, and a
+// *FactorTooLargeError if the order of x has a prime factor too large to
+// handle or if 2^n - 1, n the degree of f, cannot be factored. In the latter
+// case, its factors can be supplied with SetMersenneFactors. It returns
+// ErrRhoFailed if Pollard's rho method gives up. Panics if f is not
+// irreducible.
+func Distance(f, from, to *big.Int) (*big.Int, error) {
+	if !PolyIrreducible(f) {
+		panic(fmt.Sprintf("cannot take logarithms modulo reducible polynomial %s", f.Text(2)))
+	}
+	m := NewModulus(f)
+	var h big.Int
+	if m.Inverse(&h, from) == nil {
+		return nil, ErrNoLog
+	}
+	m.Mul(&h, &h, to)
+	return discreteLog(m, polyX, &h)
+}
+
+// DiscreteLog finds the least e >= 0 such that base^e = h mod f, where f is an
+// irreducible polynomial. It uses the Pohlig-Hellman algorithm over the
+// factors of 2^n - 1, n the degree of f, solving in each prime-order subgroup
+// with baby-step giant-step or Pollard's rho method depending on its size.
+//
+// DiscreteLog returns ErrNoLog if h is not a power of base, and a
+// *FactorTooLargeError if the order of base has a prime factor too large to
+// handle or if 2^n - 1 cannot be factored. In the latter case, its factors can
+// be supplied with SetMersenneFactors. It returns ErrRhoFailed if Pollard's
+// rho method gives up. Panics if f is not irreducible.
+func DiscreteLog(f, base, h *big.Int) (*big.Int, error) {
+	if !PolyIrreducible(f) {
+		panic(fmt.Sprintf("cannot take logarithms modulo reducible polynomial %s", f.Text(2)))
+	}
+	return discreteLog(NewModulus(f), base, h)
+}
+
+// discreteLog implements DiscreteLog modulo an irreducible polynomial.
+func discreteLog(m *Modulus, base, h *big.Int) (*big.Int, error) {
+	g := m.Reduce(new(big.Int), base)
+	y := m.Reduce(new(big.Int), h)
+	if g.Sign() == 0 || y.Sign() == 0 {
+		if g.Cmp(y) == 0 {
+			// 0^1 = 0.
+			return big.NewInt(1), nil
+		}
+		return nil, ErrNoLog
+	}
+	// Find the order of g and its factorization.
+	fs, err := tryMersenneFactors(m.Deg())
+	if err != nil {
+		return nil, err
+	}
+	N := elemOrder(m, g, fs)
+	var qs []*big.Int
+	var ks []int
+	var t, r, s big.Int
+	for _, q := range fs {
+		k := 0
+		for t.Set(N); ; k++ {
+			if s.QuoRem(&t, q, &r); r.Sign() != 0 {
+				break
+			}
+			t.Set(&s)
+		}
+		if k > 0 {
+			if q.BitLen() > rhoMaxBits {
+				return nil, &FactorTooLargeError{Factor: new(big.Int).Set(q)}
+			}
+			qs = append(qs, q)
+			ks = append(ks, k)
+		}
+	}
+	// Solve modulo each prime power and combine with the CRT.
+	x := new(big.Int)
+	mod := big.NewInt(1)
+	for i, q := range qs {
+		qk := new(big.Int).Exp(q, big.NewInt(int64(ks[i])), nil)
+		c := new(big.Int).Quo(N, qk)
+		gq := m.Exp(new(big.Int), g, c)
+		yq := m.Exp(new(big.Int), y, c)
+		xq, err := logPrimePower(m, gq, yq, q, ks[i])
+		if err != nil {
+			return nil, err
+		}
+		x = crt(x, mod, xq, qk)
+		mod.Mul(mod, qk)
+	}
+	if m.Exp(&t, g, x).Cmp(y) != 0 {
+		return nil, ErrNoLog
+	}
+	return x, nil
+}
+
+// logPrimePower finds x modulo q^k such that g^x = y, where g has order q^k.
+func logPrimePower(m *Modulus, g, y, q *big.Int, k int) (*big.Int, error) {
+	// gamma generates the subgroup of order q.
+	qk1 := new(big.Int).Exp(q, big.NewInt(int64(k-1)), nil)
+	gamma := m.Exp(new(big.Int), g, qk1)
+	x := new(big.Int)
+	qi := big.NewInt(1)
+	var gi, t big.Int
+	for i := 0; i < k; i++ {
+		// Strip the digits found so far and project into the subgroup of
+		// order q to find the next digit.
+		m.Exp(&gi, g, new(big.Int).Neg(x))
+		m.Mul(&t, &gi, y)
+		e := new(big.Int).Exp(q, big.NewInt(int64(k-1-i)), nil)
+		m.Exp(&t, &t, e)
+		d, err := logPrime(m, gamma, &t, q)
+		if err != nil {
+			return nil, err
+		}
+		x.Add(x, new(big.Int).Mul(d, qi))
+		qi.Mul(qi, q)
+	}
+	return x, nil
+}
+
+// logPrime finds x modulo the prime q such that g^x = y, where g has order q.
+func logPrime(m *Modulus, g, y, q *big.Int) (*big.Int, error) {
+	if y.Cmp(oneP) == 0 {
+		return new(big.Int), nil
+	}
+	if q.BitLen() <= bsgsMaxBits {
+		return bsgs(m, g, y, q)
+	}
+	return rhoLog(m, g, y, q)
+}
+
+// bsgs solves g^x = y for x modulo q using baby-step giant-step.
+func bsgs(m *Modulus, g, y, q *big.Int) (*big.Int, error) {
+	s := new(big.Int).Sqrt(q)
+	n := s.Int64() + 1
+	tab := make(map[string]int64, n)
+	a := big.NewInt(1)
+	for j := int64(0); j < n; j++ {
+		if _, ok := tab[string(a.Bytes())]; !ok {
+			tab[string(a.Bytes())] = j
+		}
+		m.Mul(a, a, g)
+	}
+	// a is now g^n; giant steps multiply by its inverse.
+	m.Inverse(a, a)
+	b := new(big.Int).Set(y)
+	for i := int64(0); i < n; i++ {
+		if j, ok := tab[string(b.Bytes())]; ok {
+			x := big.NewInt(i*n + j)
+			return x.Mod(x, q), nil
+		}
+		m.Mul(b, b, a)
+	}
+	return nil, ErrNoLog
+}
+
+// rhoLog solves g^x = y for x modulo q using Pollard's rho method with
+// Floyd's cycle detection. Returns ErrRhoFailed if no walk finds a collision
+// that determines x.
+func rhoLog(m *Modulus, g, y, q *big.Int) (*big.Int, error) {
+	rng := rand.New(rand.NewSource(1))
+	type point struct {
+		z, a, b *big.Int
+	}
+	// step advances a point z = g^a y^b of the walk, choosing the step by the
+	// low bits of z.
+	step := func(p *point) {
+		switch p.z.Bits()[0] % 3 {
+		case 0:
+			m.Mul(p.z, p.z, g)
+			p.a.Add(p.a, oneP).Mod(p.a, q)
+		case 1:
+			m.Sqr(p.z, p.z)
+			p.a.Lsh(p.a, 1).Mod(p.a, q)
+			p.b.Lsh(p.b, 1).Mod(p.b, q)
+		default:
+			m.Mul(p.z, p.z, y)
+			p.b.Add(p.b, oneP).Mod(p.b, q)
+		}
+	}
+	limit := new(big.Int).Lsh(new(big.Int).Sqrt(q), 4)
+	for tries := 0; tries < 8; tries++ {
+		a0 := new(big.Int).Rand(rng, q)
+		b0 := new(big.Int).Rand(rng, q)
+		z := m.Exp(new(big.Int), g, a0)
+		m.Mul(z, z, m.Exp(new(big.Int), y, b0))
+		t := point{z, a0, b0}
+		h := point{new(big.Int).Set(z), new(big.Int).Set(a0), new(big.Int).Set(b0)}
+		for i := new(big.Int); i.Cmp(limit) < 0; i.Add(i, oneP) {
+			step(&t)
+			step(&h)
+			step(&h)
+			if t.z.Cmp(h.z) != 0 {
+				continue
+			}
+			// g^(ta) y^(tb) = g^(ha) y^(hb), so x = (ta-ha)/(hb-tb).
+			db := new(big.Int).Sub(h.b, t.b)
+			db.Mod(db, q)
+			if db.Sign() == 0 {
+				break
+			}
+			x := new(big.Int).Sub(t.a, h.a)
+			x.Mul(x, db.ModInverse(db, q)).Mod(x, q)
+			if m.Exp(new(big.Int), g, x).Cmp(y) != 0 {
+				return nil, ErrNoLog
+			}
+			return x, nil
+		}
+	}
+	return nil, ErrRhoFailed
+}
+
+// crt combines x = a mod m and x = b mod n, with m and n coprime, into x mod
+// m*n.
+func crt(a, m, b, n *big.Int) *big.Int {
+	// x = a + m*((b-a)/m mod n)
+	t := new(big.Int).Sub(b, a)
+	inv := new(big.Int).ModInverse(new(big.Int).Mod(m, n), n)
+	if inv == nil {
+		// n is 1.
+		return new(big.Int).Set(a)
+	}
+	t.Mul(t, inv).Mod(t, n)
+	return t.Mul(t, m).Add(t, a)
+}
@@ -0,0 +1,111 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// firstPentanomial returns an irreducible pentanomial of degree n.
+func firstPentanomial(n int) *big.Int {
+	return SearchPentanomials(n, &SearchOpts{Limit: 1})[0]
+}
+
+func TestDiscreteLog(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	one := big.NewInt(1)
+	polys := []*big.Int{
+		big.NewInt(0x13),  // x^4 + x + 1, primitive
+		big.NewInt(0x1f),  // x^4 + x^3 + x^2 + x + 1, x has order 5
+		big.NewInt(0x11b), // x^8 + x^4 + x^3 + x + 1, x has order 51
+		firstPentanomial(32),
+		firstPentanomial(48),
+		// 2^59 - 1 has the 42-bit prime factor 3203431780337, which needs
+		// Pollard's rho.
+		firstPentanomial(59),
+	}
+	for _, f := range polys {
+		m := NewModulus(f)
+		n := PolyDeg(f)
+		if n > 48 && testing.Short() {
+			continue
+		}
+		// Rho is slow, so take a single sample of the largest case.
+		k := 4
+		if n > 48 {
+			k = 1
+		}
+		for i := 0; i < k; i++ {
+			g := randPoly(r, r.Intn(n))
+			if g.Sign() == 0 {
+				g.SetInt64(2)
+			}
+			e := new(big.Int).Rand(r, new(big.Int).Lsh(one, uint(n)))
+			h := m.Exp(new(big.Int), g, e)
+			x, err := DiscreteLog(f, g, h)
+			if err != nil {
+				t.Errorf("DiscreteLog(%v, %v, %v): %v", f, g, h, err)
+				continue
+			}
+			if y := m.Exp(new(big.Int), g, x); y.Cmp(h) != 0 {
+				t.Errorf("DiscreteLog(%v, %v, %v) = %v, but g^x = %v", f, g, h, x, y)
+			}
+			N := elemOrder(m, g, mersenneFactors(n))
+			if x.Sign() < 0 || x.Cmp(N) >= 0 {
+				t.Errorf("DiscreteLog(%v, %v, %v) = %v is not less than the order %v", f, g, h, x, N)
+			}
+			from := randPoly(r, r.Intn(n))
+			if from.Sign() == 0 {
+				from.SetInt64(1)
+			}
+			to := m.Mul(new(big.Int), from, m.Exp(new(big.Int), big.NewInt(2), e))
+			k, err := Distance(f, from, to)
+			if err != nil {
+				t.Errorf("Distance(%v, %v, %v): %v", f, from, to, err)
+				continue
+			}
+			if y := m.Mul(new(big.Int), from, m.Exp(new(big.Int), big.NewInt(2), k)); y.Cmp(to) != 0 {
+				t.Errorf("Distance(%v, %v, %v) = %v does not reach it", f, from, to, k)
+			}
+		}
+	}
+}
+
+func TestDiscreteLogErrors(t *testing.T) {
+	// x has order 5 modulo x^4 + x^3 + x^2 + x + 1, so x + 1 is not a power
+	// of x.
+	if _, err := DiscreteLog(big.NewInt(0x1f), big.NewInt(2), big.NewInt(3)); err != ErrNoLog {
+		t.Errorf("log of x+1 to base x: got error %v, want ErrNoLog", err)
+	}
+	// 2^61 - 1 is prime, so every element other than 0 and 1 has an order
+	// too large to handle.
+	f := SearchTrinomials(61, &SearchOpts{Limit: 1})
+	if len(f) == 0 {
+		f = []*big.Int{firstPentanomial(61)}
+	}
+	_, err := DiscreteLog(f[0], big.NewInt(2), big.NewInt(3))
+	if e, ok := err.(*FactorTooLargeError); !ok || e.Composite || e.Factor.BitLen() != 61 {
+		t.Errorf("log modulo degree 61 polynomial: got error %v, want prime factor too large", err)
+	}
+}
+
+func TestFactorIntTooLarge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("rho takes seconds to give up")
+	}
+	// The product of two Mersenne primes near 2^61 and 2^89 has no factor
+	// that rho can find within maxRhoSteps.
+	one := big.NewInt(1)
+	p := new(big.Int).Sub(new(big.Int).Lsh(one, 61), one)
+	q := new(big.Int).Sub(new(big.Int).Lsh(one, 89), one)
+	n := new(big.Int).Mul(p, q)
+	_, err := factorInt(n)
+	if e, ok := err.(*FactorTooLargeError); !ok || !e.Composite || e.Factor.Cmp(n) != 0 {
+		t.Errorf("factorInt(%v): got error %v, want composite factor too large", n, err)
+	}
+	// Factors found by trial division are still divided out.
+	_, err = factorInt(new(big.Int).Mul(n, big.NewInt(15)))
+	if e, ok := err.(*FactorTooLargeError); !ok || e.Factor.Cmp(n) != 0 {
+		t.Errorf("factorInt(15 n): got error %v, want composite factor %v too large", err, n)
+	}
+}
@@ -0,0 +1,174 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// PolyEval evaluates the polynomial p at the square binary matrix A, giving
+// p(A) = c_0 I + c_1 A + c_2 A^2 + .... For example, the jump matrix that
+// advances a generator with transition matrix A by k steps is PolyEval(x^k
+// mod f, A), where f is the characteristic polynomial of A.
+//
+// Dense polynomials are evaluated with the Paterson-Stockmeyer method, which
+// uses about 2*sqrt(deg p) matrix products instead of the deg p of Horner's
+// rule. If A is an SM and p has few enough terms that computing each power
+// of A directly takes fewer products, the powers are formed by repeated
+// squaring instead. In either case, if A is an SM, the result is an SM. If p
+// is zero and A is not an SM, the result is Z. Panics if A is not square.
+func PolyEval(p *big.Int, A M) M {
+	n, c := A.Size()
+	if n != c {
+		panic(fmt.Sprintf("cannot evaluate polynomial at %dx%d matrix: matrix must be square", n, c))
+	}
+	d := PolyDeg(p)
+	_, sparse := A.(*SM)
+	if d < 0 {
+		if sparse {
+			return NewSparse(n, n)
+		}
+		return Zeros(n, n)
+	}
+	k := 1
+	for k*k < d+1 {
+		k++
+	}
+	if sparse && chainCost(p) < k-1+d/k {
+		return evalChain(p, A.(*SM))
+	}
+	return evalPS(p, A, k, sparse)
+}
+
+// chainCost returns the number of matrix products needed to evaluate p by
+// forming each power of the matrix from the previous one.
+func chainCost(p *big.Int) int {
+	r, last := 0, 0
+	for i := 0; i < p.BitLen(); i++ {
+		if p.Bit(i) == 0 {
+			continue
+		}
+		if g := uint(i - last); g > 0 {
+			// Raising to the gap takes one product per bit after the first
+			// and one per extra set bit, then one to multiply it in.
+			r += bits.Len(g) + bits.OnesCount(g) - 1
+		}
+		last = i
+	}
+	return r
+}
+
+// evalChain evaluates p at A by adding up each power of A corresponding to a
+// term of p.
+func evalChain(p *big.Int, A *SM) M {
+	n, _ := A.Size()
+	var P M = Sparse(Eye(n, n))
+	var r M = NewSparse(n, n)
+	last := 0
+	for i := 0; i < p.BitLen(); i++ {
+		if p.Bit(i) == 0 {
+			continue
+		}
+		if i > last {
+			P = FMul(P, matPow(A, i-last))
+			last = i
+		}
+		r = fAdd(r, P)
+	}
+	return r
+}
+
+// evalPS evaluates p at A using the Paterson-Stockmeyer method with k baby
+// steps. If sparse is true, all intermediate matrices are SMs; otherwise,
+// they are whatever FMul produces.
+func evalPS(p *big.Int, A M, k int, sparse bool) M {
+	n, _ := A.Size()
+	d := PolyDeg(p)
+	// baby holds I, A, A^2, ..., A^(k-1).
+	baby := make([]M, k)
+	baby[0] = Full(Eye(n, n))
+	if sparse {
+		baby[0] = Sparse(Eye(n, n))
+	}
+	for i := 1; i < k; i++ {
+		baby[i] = FMul(baby[i-1], A)
+	}
+	B := FMul(baby[k-1], A)
+	// q returns the sum of c_(jk+i) A^i over i < k.
+	q := func(j int) M {
+		var r M
+		if sparse {
+			r = NewSparse(n, n)
+		} else {
+			r = NewFull(n, n)
+		}
+		for i := 0; i < k; i++ {
+			if p.Bit(j*k+i) != 0 {
+				r = fAdd(r, baby[i])
+			}
+		}
+		return r
+	}
+	// Horner's rule in A^k over the blocks of coefficients.
+	m := d / k
+	r := q(m)
+	for j := m - 1; j >= 0; j-- {
+		r = fAdd(FMul(r, B), q(j))
+	}
+	return r
+}
+
+// matPow computes A^e for e >= 1 by repeated squaring.
+func matPow(A M, e int) M {
+	var r M
+	P := A
+	for {
+		if e&1 != 0 {
+			if r == nil {
+				r = P
+			} else {
+				r = FMul(r, P)
+			}
+		}
+		if e >>= 1; e == 0 {
+			return r
+		}
+		P = FMul(P, P)
+	}
+}
+
+// fAdd adds two binary matrices of the same size into a new matrix. If both
+// are SM, the result is SM; otherwise, it is FM.
+func fAdd(A, B M) M {
+	ar, ac := A.Size()
+	br, bc := B.Size()
+	if ar != br || ac != bc {
+		panic(fmt.Sprintf("size mismatch: %dx%d + %dx%d", ar, ac, br, bc))
+	}
+	if x, ok := A.(*SM); ok {
+		if y, ok := B.(*SM); ok {
+			C := Sparse(x)
+			for k, v := range y.v {
+				if C.v[k] ^= v; C.v[k] == 0 {
+					delete(C.v, k)
+				}
+			}
+			return C
+		}
+	}
+	C := Full(A)
+	if y, ok := B.(*FM); ok {
+		C.v.Xor(C.v, y.v)
+		// Both had the bit past the end set.
+		C.v.SetBit(C.v, ar*ac, 1)
+		return C
+	}
+	for c := 1; c <= bc; c++ {
+		for r := 1; r <= br; r++ {
+			if check01(B.At(r, c)) != 0 {
+				C.AddAt(r, c, oneP)
+			}
+		}
+	}
+	return C
+}
@@ -0,0 +1,53 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// evalHorner evaluates p at A by Horner's rule.
+func evalHorner(p *big.Int, A M) M {
+	n, _ := A.Size()
+	var R M = NewSparse(n, n)
+	for i := p.BitLen() - 1; i >= 0; i-- {
+		R = FMul(R, A)
+		if p.Bit(i) != 0 {
+			R = fAdd(R, Eye(n, n))
+		}
+	}
+	return R
+}
+
+func TestPolyEval(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 5, 17} {
+		A := randSparse(r, n, n, 2*n)
+		for _, p := range []*big.Int{
+			big.NewInt(1),
+			big.NewInt(2),
+			big.NewInt(0x8001),
+			new(big.Int).SetBit(big.NewInt(1), 200, 1),
+			randPoly(r, 40),
+			randPoly(r, 100),
+		} {
+			want := evalHorner(p, A)
+			got := PolyEval(p, A)
+			if _, ok := got.(*SM); !ok {
+				t.Errorf("PolyEval(%v) of %dx%d SM has type %T", p, n, n, got)
+			}
+			if !sameElements(got, want) {
+				t.Errorf("PolyEval(%v) of %dx%d SM is wrong", p, n, n)
+			}
+			if !sameElements(PolyEval(p, Full(A)), want) {
+				t.Errorf("PolyEval(%v) of %dx%d FM is wrong", p, n, n)
+			}
+		}
+		if C, ok := PolyEval(new(big.Int), A).(*SM); !ok || len(C.v) != 0 {
+			t.Errorf("PolyEval(0) of %dx%d SM is not a zero SM", n, n)
+		}
+		if _, ok := PolyEval(new(big.Int), Full(A)).(Z); !ok {
+			t.Errorf("PolyEval(0) of %dx%d FM is not Z", n, n)
+		}
+	}
+}
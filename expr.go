@@ -0,0 +1,229 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Sum is an immutable lazy sum of binary matrices of the same size. The terms
+// are kept as given, and the sum is formed only by Eval or by conversion to
+// another type. MulVec multiplies by each term separately, so the sum is
+// never materialized.
+type Sum struct {
+	immutableM
+	// r and c are the size of the matrix.
+	r, c int
+	// t holds the terms.
+	t []M
+}
+
+// LazySum creates the sum of the given binary matrices without evaluating it.
+// Terms which are themselves Sum contribute their terms directly. Panics if
+// there are no terms or if the terms differ in size.
+func LazySum(terms ...M) Sum {
+	if len(terms) == 0 {
+		panic("cannot make empty Sum")
+	}
+	r, c := terms[0].Size()
+	var t []M
+	for _, a := range terms {
+		if ar, ac := a.Size(); ar != r || ac != c {
+			panic(fmt.Sprintf("dimension mismatch: %dx%d + %dx%d", r, c, ar, ac))
+		}
+		if s, ok := a.(Sum); ok {
+			t = append(t, s.t...)
+		} else {
+			t = append(t, a)
+		}
+	}
+	return Sum{r: r, c: c, t: t}
+}
+
+// Size returns the size of the matrix.
+func (s Sum) Size() (rows, cols int) {
+	return s.r, s.c
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column, computed from the element of each term. The returned value is a
+// shared constant and must not be modified. Panics if any term has an element
+// there other than 0 or 1.
+func (s Sum) At(r, c int) *big.Int {
+	if r <= 0 || r > s.r || c <= 0 || c > s.c {
+		panic(fmt.Sprintf("index (%d,%d) out of bounds (size %dx%d)", r, c, s.r, s.c))
+	}
+	var b uint8
+	for _, a := range s.t {
+		b ^= check01(a.At(r, c))
+	}
+	return to01(b != 0)
+}
+
+// Terms returns the terms of the sum. The slice is a copy, but the matrices are
+// not.
+func (s Sum) Terms() []M {
+	return append([]M(nil), s.t...)
+}
+
+// Eval computes the sum. The result is SM, or LSM if it is too large for SM, if
+// every term is sparse or Z, and DM otherwise.
+func (s Sum) Eval() M {
+	sparse := true
+	for _, a := range s.t {
+		if _, ok := a.(Z); !ok && !isSparse(a) {
+			sparse = false
+		}
+	}
+	if !sparse {
+		C := Dense(s.t[0])
+		for _, a := range s.t[1:] {
+			xorRow(C.v, Dense(a).v)
+		}
+		return C
+	}
+	C := NewLSparse(s.r, s.c)
+	for _, a := range s.t {
+		for k := range LSparse(a).v {
+			if C.v[k] ^= 1; C.v[k] == 0 {
+				delete(C.v, k)
+			}
+		}
+	}
+	if s.r > 65535 || s.c > 65535 {
+		return C
+	}
+	return Sparse(C)
+}
+
+// mulVec returns the product of the matrix by a column vector as the sum of
+// the products by each term.
+func (s Sum) mulVec(x *big.Int) *big.Int {
+	y := new(big.Int)
+	for _, a := range s.t {
+		y.Xor(y, MulVec(a, x))
+	}
+	return y
+}
+
+// Product is an immutable lazy product of binary matrices. The factors are
+// kept as given, and the product is formed only by Eval or by conversion to
+// another type. MulVec multiplies by each factor in turn from the right, so
+// black-box algorithms can run on a product of transitions without forming
+// it.
+type Product struct {
+	immutableM
+	// r and c are the size of the matrix.
+	r, c int
+	// f holds the factors from left to right.
+	f []M
+}
+
+// LazyProduct creates the product of the given binary matrices without
+// evaluating it. Factors which are themselves Product contribute their factors
+// directly. Panics if there are no factors or if the inner dimensions of any
+// adjacent factors are not equal.
+func LazyProduct(factors ...M) Product {
+	if len(factors) == 0 {
+		panic("cannot make empty Product")
+	}
+	r, c := factors[0].Size()
+	var f []M
+	for i, a := range factors {
+		ar, ac := a.Size()
+		if i > 0 && ar != c {
+			panic(fmt.Sprintf("inner dimension mismatch: %dx%d * %dx%d", r, c, ar, ac))
+		}
+		c = ac
+		if p, ok := a.(Product); ok {
+			f = append(f, p.f...)
+		} else {
+			f = append(f, a)
+		}
+	}
+	return Product{r: r, c: c, f: f}
+}
+
+// Size returns the size of the matrix.
+func (p Product) Size() (rows, cols int) {
+	return p.r, p.c
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The column is computed by multiplying a unit vector through
+// every factor, so each call costs as much as MulVec. The returned value is a
+// shared constant and must not be modified. Panics if any factor has an
+// element other than 0 or 1.
+func (p Product) At(r, c int) *big.Int {
+	if r <= 0 || r > p.r || c <= 0 || c > p.c {
+		panic(fmt.Sprintf("index (%d,%d) out of bounds (size %dx%d)", r, c, p.r, p.c))
+	}
+	x := new(big.Int).SetBit(new(big.Int), c-1, 1)
+	return to01(p.mulVec(x).Bit(r-1) != 0)
+}
+
+// Factors returns the factors of the product from left to right. The slice is
+// a copy, but the matrices are not.
+func (p Product) Factors() []M {
+	return append([]M(nil), p.f...)
+}
+
+// Eval computes the product from left to right with FMul. If there is only
+// one factor, it is returned as is.
+func (p Product) Eval() M {
+	C := p.f[0]
+	for _, a := range p.f[1:] {
+		C = FMul(C, a)
+	}
+	return C
+}
+
+// mulVec returns the product of the matrix by a column vector, applying the
+// factors from right to left.
+func (p Product) mulVec(x *big.Int) *big.Int {
+	for i := len(p.f) - 1; i >= 0; i-- {
+		x = MulVec(p.f[i], x)
+	}
+	return x
+}
+
+// fMulLazy multiplies two matrices of which at least one is Sum or Product.
+// The factors of a Product are applied to the other argument one at a time,
+// and each term of a Sum is multiplied by it separately.
+func fMulLazy(A, B M) M {
+	switch x := A.(type) {
+	case Product:
+		for i := len(x.f) - 1; i >= 0; i-- {
+			B = FMul(x.f[i], B)
+		}
+		return B
+	case Sum:
+		t := make([]M, len(x.t))
+		for i, a := range x.t {
+			t[i] = FMul(a, B)
+		}
+		return LazySum(t...).Eval()
+	}
+	switch y := B.(type) {
+	case Product:
+		for _, b := range y.f {
+			A = FMul(A, b)
+		}
+		return A
+	case Sum:
+		t := make([]M, len(y.t))
+		for i, b := range y.t {
+			t[i] = FMul(A, b)
+		}
+		return LazySum(t...).Eval()
+	}
+	panic("fMulLazy called without Sum or Product")
+}
+
+// isLazy returns whether m is Sum or Product.
+func isLazy(m M) bool {
+	switch m.(type) {
+	case Sum, Product:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package gof2
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// sumNaive adds binary matrices of the same size into a new FM.
+func sumNaive(terms ...M) *FM {
+	rows, cols := terms[0].Size()
+	C := NewFull(rows, cols)
+	for _, a := range terms {
+		for i := 1; i <= rows; i++ {
+			for j := 1; j <= cols; j++ {
+				C.AddAt(i, j, a.At(i, j))
+			}
+		}
+	}
+	return C
+}
+
+func TestSum(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sz := range [][2]int{{1, 1}, {5, 9}, {70, 130}} {
+		n, m := sz[0], sz[1]
+		X, Y, W := randSparse(r, n, m, n*m/4), randSparse(r, n, m, n*m/4), randSparse(r, n, m, n*m/4)
+		cases := []struct {
+			terms  []M
+			sparse bool
+		}{
+			{[]M{X}, true},
+			{[]M{X, Y, Eye(n, m), Zeros(n, m)}, true},
+			{[]M{X, CompressRows(Y), LSparse(W)}, true},
+			{[]M{X, Full(Y)}, false},
+			{[]M{Dense(X), Dense(Y), W}, false},
+			{[]M{LazySum(X, Y), W}, true},
+		}
+		for _, c := range cases {
+			S := LazySum(c.terms...)
+			want := sumNaive(c.terms...)
+			if !sameElements(S, want) {
+				t.Fatalf("At of sum of %d terms of size %dx%d is wrong", len(c.terms), n, m)
+			}
+			E := S.Eval()
+			_, sm := E.(*SM)
+			_, dm := E.(*DM)
+			if !sameElements(E, want) || sm != c.sparse || dm == c.sparse {
+				t.Errorf("Eval of sum of %d terms of size %dx%d is wrong or has type %T", len(c.terms), n, m, E)
+			}
+			if !sameElements(Full(S), want) || !sameElements(Sparse(S), want) || !sameElements(Dense(S), want) || !sameElements(LSparse(S), want) {
+				t.Errorf("conversions of sum of %d terms of size %dx%d disagree", len(c.terms), n, m)
+			}
+			checkMulVec(t, r, "Sum", S)
+			B := randSparse(r, m, 7, m*7/4)
+			if !sameElements(FMul(S, B), fMulNaive(want, B)) || !sameElements(FMul(S, Full(B)), fMulNaive(want, B)) {
+				t.Errorf("product of sum of size %dx%d is wrong", n, m)
+			}
+			A := randSparse(r, 7, n, n*7/4)
+			if !sameElements(FMul(A, S), fMulNaive(A, want)) {
+				t.Errorf("product by sum of size %dx%d is wrong", n, m)
+			}
+		}
+		// Nested sums are flattened.
+		if k := len(LazySum(LazySum(X, Y), LazySum(W, X)).Terms()); k != 4 {
+			t.Errorf("nested sum has %d terms, want 4", k)
+		}
+	}
+	// Sums too large for SM evaluate to LSM.
+	L := largeSparse(r, largeN, 3)
+	E := LazySum(L, Eye(largeN, 3), L).Eval()
+	if _, ok := E.(*LSM); !ok || !sameElements(LSparse(E), LSparse(Eye(largeN, 3))) {
+		t.Errorf("large sum is wrong or has type %T", E)
+	}
+}
+
+func TestProduct(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, dims := range [][]int{{1, 1}, {5, 9, 3}, {9, 70, 130, 65, 8}} {
+		var f []M
+		for i := 0; i+1 < len(dims); i++ {
+			S := randSparse(r, dims[i], dims[i+1], dims[i]*dims[i+1]/4)
+			switch i % 3 {
+			case 0:
+				f = append(f, S)
+			case 1:
+				f = append(f, Full(S))
+			case 2:
+				f = append(f, Dense(S))
+			}
+		}
+		want := Full(f[0])
+		for _, a := range f[1:] {
+			want = fMulNaive(want, a)
+		}
+		P := LazyProduct(f...)
+		if !sameElements(P, want) || !sameElements(P.Eval(), want) {
+			t.Errorf("product of %d factors of sizes %v is wrong", len(f), dims)
+		}
+		if !sameElements(Full(P), want) || !sameElements(Sparse(P), want) || !sameElements(Dense(P), want) || !sameElements(LSparse(P), want) {
+			t.Errorf("conversions of product of sizes %v disagree", dims)
+		}
+		checkMulVec(t, r, "Product", P)
+		n, m := P.Size()
+		B := randSparse(r, m, 7, m*7/4)
+		if !sameElements(FMul(P, B), fMulNaive(want, B)) {
+			t.Errorf("product of Product of sizes %v is wrong", dims)
+		}
+		A := randSparse(r, 7, n, n*7/4)
+		if !sameElements(FMul(A, P), fMulNaive(A, want)) {
+			t.Errorf("product by Product of sizes %v is wrong", dims)
+		}
+		// Nested products are flattened, and sums of products evaluate.
+		if k := len(LazyProduct(P, LazyProduct(Eye(m, m), Eye(m, m))).Factors()); k != len(f)+2 {
+			t.Errorf("nested product has %d factors, want %d", k, len(f)+2)
+		}
+		S := LazySum(P, LazyProduct(Eye(n, n), P))
+		if E := S.Eval(); !sameElements(E, Zeros(n, m)) {
+			t.Errorf("P + I*P of sizes %v is not zero", dims)
+		}
+	}
+}
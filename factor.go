@@ -0,0 +1,234 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+)
+
+// Factor is an irreducible factor of a polynomial along with its
+// multiplicity.
+type Factor struct {
+	// P is the irreducible factor.
+	P *big.Int
+	// E is the multiplicity of P.
+	E int
+}
+
+// PolyFactor factors f into irreducible polynomials over GF(2). The factors
+// are sorted by degree, then by value. The factorization of 1 is empty.
+// Panics if f is zero.
+//
+// The factorization proceeds by square-free factorization, then distinct-degree
+// factorization, then Cantor-Zassenhaus equal-degree splitting. Square-free
+// parts which are irreducible are recognized early with Rabin's test, so
+// irreducible polynomials of large degree are cheap to factor.
+func PolyFactor(f *big.Int) []Factor {
+	if f.Sign() == 0 {
+		panic("cannot factor zero polynomial")
+	}
+	var r []Factor
+	// The randomness in equal-degree splitting only affects running time, so
+	// a fixed seed keeps results reproducible.
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range squareFree(new(big.Int).Abs(f)) {
+		if PolyIrreducible(s.P) {
+			r = append(r, s)
+			continue
+		}
+		for _, d := range distinctDegree(s.P) {
+			for _, p := range equalDegree(d.P, d.E, rng) {
+				r = append(r, Factor{p, s.E})
+			}
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		di, dj := PolyDeg(r[i].P), PolyDeg(r[j].P)
+		if di != dj {
+			return di < dj
+		}
+		return r[i].P.Cmp(r[j].P) < 0
+	})
+	return r
+}
+
+// PolyIrreducible reports whether f is irreducible over GF(2) using Rabin's
+// test: f of degree n is irreducible if and only if x^(2^n) = x mod f and
+// x^(2^(n/q)) - x is coprime to f for each prime q dividing n. This costs n
+// squarings modulo f plus one GCD per prime divisor of n.
+func PolyIrreducible(f *big.Int) bool {
+	n := PolyDeg(f)
+	switch {
+	case n < 1:
+		return false
+	case n == 1:
+		return true
+	case f.Bit(0) == 0:
+		// Divisible by x.
+		return false
+	}
+	if weight(f)%2 == 0 {
+		// Divisible by x+1.
+		return false
+	}
+	m := NewModulus(f)
+	// check holds the values of k for which x^(2^k) - x must be coprime to f.
+	check := make(map[int]bool)
+	for _, q := range smallPrimeFactors(n) {
+		check[n/q] = true
+	}
+	h := big.NewInt(2)
+	var t, g big.Int
+	for k := 1; k <= n; k++ {
+		m.Sqr(h, h)
+		if check[k] {
+			t.Xor(h, polyX)
+			if PolyDeg(PolyGCD(&g, &t, f)) != 0 {
+				return false
+			}
+		}
+	}
+	return h.Cmp(polyX) == 0
+}
+
+// polyX is the polynomial x.
+var polyX = big.NewInt(2)
+
+// squareFree returns the square-free factorization of f, which must be
+// non-zero. Each returned polynomial is square-free, they are pairwise
+// coprime, and f is the product of each raised to its multiplicity.
+func squareFree(f *big.Int) []Factor {
+	var r []Factor
+	if PolyDeg(f) < 1 {
+		return nil
+	}
+	d := polyDeriv(f)
+	if d.Sign() == 0 {
+		// f is a square.
+		for _, s := range squareFree(polySqrt(f)) {
+			r = append(r, Factor{s.P, 2 * s.E})
+		}
+		return r
+	}
+	c := PolyGCD(new(big.Int), f, d)
+	w, _ := PolyDivMod(nil, nil, f, c)
+	for i := 1; PolyDeg(w) > 0; i++ {
+		y := PolyGCD(new(big.Int), w, c)
+		z, _ := PolyDivMod(nil, nil, w, y)
+		if PolyDeg(z) > 0 {
+			r = append(r, Factor{z, i})
+		}
+		w = y
+		PolyDivMod(c, nil, c, y)
+	}
+	if PolyDeg(c) > 0 {
+		// What remains has only squares of factors, so its derivative is 0.
+		for _, s := range squareFree(polySqrt(c)) {
+			r = append(r, Factor{s.P, 2 * s.E})
+		}
+	}
+	return r
+}
+
+// distinctDegree splits a square-free polynomial f into products of
+// irreducible factors of equal degree. The E field of each result holds the
+// degree of its irreducible factors.
+func distinctDegree(f *big.Int) []Factor {
+	var r []Factor
+	g := new(big.Int).Set(f)
+	m := NewModulus(g)
+	h := big.NewInt(2)
+	var t big.Int
+	for d := 1; 2*d <= PolyDeg(g); d++ {
+		// Each irreducible factor of degree d divides x^(2^d) - x.
+		m.Sqr(h, h)
+		u := PolyGCD(new(big.Int), g, t.Xor(h, polyX))
+		if PolyDeg(u) > 0 {
+			r = append(r, Factor{u, d})
+			PolyDivMod(g, nil, g, u)
+			if PolyDeg(g) < 1 {
+				return r
+			}
+			m = NewModulus(g)
+			m.Reduce(h, h)
+		}
+	}
+	if PolyDeg(g) > 0 {
+		r = append(r, Factor{g, PolyDeg(g)})
+	}
+	return r
+}
+
+// equalDegree splits f, a product of distinct irreducible polynomials each of
+// degree d, into its factors.
+func equalDegree(f *big.Int, d int, rng *rand.Rand) []*big.Int {
+	n := PolyDeg(f)
+	if n <= d {
+		return []*big.Int{f}
+	}
+	m := NewModulus(f)
+	lim := new(big.Int).Lsh(oneP, uint(n))
+	a, t := new(big.Int), new(big.Int)
+	for {
+		// The trace from GF(2^d) to GF(2) of a random element is 0 in about
+		// half of the factors' residue fields, so its GCD with f usually
+		// splits f.
+		a.Rand(rng, lim)
+		t.Set(a)
+		for i := 1; i < d; i++ {
+			m.Sqr(a, a)
+			t.Xor(t, a)
+		}
+		u := PolyGCD(new(big.Int), f, t)
+		if k := PolyDeg(u); k > 0 && k < n {
+			v, _ := PolyDivMod(nil, nil, f, u)
+			return append(equalDegree(u, d, rng), equalDegree(v, d, rng)...)
+		}
+	}
+}
+
+// polyDeriv returns the formal derivative of f.
+func polyDeriv(f *big.Int) *big.Int {
+	v := append([]big.Word(nil), f.Bits()...)
+	// The derivative of x^i is x^(i-1) for odd i and 0 for even i.
+	odd := big.Word(^uint(0) / 3 << 1)
+	for i := range v {
+		v[i] &= odd
+	}
+	r := new(big.Int).SetBits(v)
+	return r.Rsh(r, 1)
+}
+
+// polySqrt returns the square root of f, which must have only even-degree
+// terms.
+func polySqrt(f *big.Int) *big.Int {
+	if polyDeriv(f).Sign() != 0 {
+		panic(fmt.Sprintf("polynomial %s is not a square", f.Text(2)))
+	}
+	r := new(big.Int)
+	for i := 0; i < f.BitLen(); i += 2 {
+		if f.Bit(i) != 0 {
+			r.SetBit(r, i/2, 1)
+		}
+	}
+	return r
+}
+
+// smallPrimeFactors returns the distinct prime factors of n in increasing
+// order.
+func smallPrimeFactors(n int) []int {
+	var r []int
+	for p := 2; p*p <= n; p++ {
+		if n%p == 0 {
+			r = append(r, p)
+			for n%p == 0 {
+				n /= p
+			}
+		}
+	}
+	if n > 1 {
+		r = append(r, n)
+	}
+	return r
+}
@@ -0,0 +1,90 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// irreducibleNaive reports whether f is irreducible by trial division by all
+// polynomials of at most half its degree.
+func irreducibleNaive(f *big.Int) bool {
+	n := PolyDeg(f)
+	if n < 1 {
+		return false
+	}
+	var d, r big.Int
+	for k := int64(2); PolyDeg(d.SetInt64(k)) <= n/2; k++ {
+		if PolyMod(&r, f, &d).Sign() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPolyIrreducible(t *testing.T) {
+	// The number of irreducible polynomials of each degree from 1 to 10.
+	counts := []int{2, 1, 2, 3, 6, 9, 18, 30, 56, 99}
+	var f big.Int
+	for n, want := range counts {
+		n++
+		got := 0
+		for k := int64(1) << uint(n); k < 1<<uint(n+1); k++ {
+			f.SetInt64(k)
+			irr := PolyIrreducible(&f)
+			if irr != irreducibleNaive(&f) {
+				t.Fatalf("PolyIrreducible(%b) = %t", k, irr)
+			}
+			if irr {
+				got++
+			}
+		}
+		if got != want {
+			t.Errorf("%d irreducible polynomials of degree %d, want %d", got, n, want)
+		}
+	}
+}
+
+func TestPolyFactor(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var cases []*big.Int
+	for i := 0; i < 50; i++ {
+		cases = append(cases, randPoly(r, r.Intn(120)))
+	}
+	// Products with repeated factors, including x.
+	for i := 0; i < 20; i++ {
+		f := big.NewInt(1)
+		for j := 0; j < 4; j++ {
+			p := randPoly(r, r.Intn(10)+1)
+			for e := r.Intn(4); e >= 0; e-- {
+				PolyMul(f, f, p)
+			}
+		}
+		cases = append(cases, f)
+	}
+	cases = append(cases, big.NewInt(1), big.NewInt(2), big.NewInt(0x10))
+	for _, f := range cases {
+		fs := PolyFactor(f)
+		prod := big.NewInt(1)
+		for i, p := range fs {
+			if PolyDeg(p.P) <= 24 && !irreducibleNaive(p.P) || !PolyIrreducible(p.P) {
+				t.Errorf("factor %v of %v is reducible", p.P, f)
+			}
+			if p.E <= 0 {
+				t.Errorf("factor %v of %v has multiplicity %d", p.P, f, p.E)
+			}
+			if i > 0 {
+				q := fs[i-1].P
+				if PolyDeg(q) > PolyDeg(p.P) || PolyDeg(q) == PolyDeg(p.P) && q.Cmp(p.P) >= 0 {
+					t.Errorf("factors %v, %v of %v are out of order", q, p.P, f)
+				}
+			}
+			for e := 0; e < p.E; e++ {
+				PolyMul(prod, prod, p.P)
+			}
+		}
+		if prod.Cmp(f) != 0 {
+			t.Errorf("factors of %v multiply to %v", f, prod)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Field is the finite field GF(2^n), constructed as GF(2)[x]/(p) for an
+// irreducible polynomial p of degree n. Elements are polynomials of degree
+// less than n, represented as big ints the same way as any other polynomial.
+// Arguments to Field methods need not be reduced modulo p; results always
+// are.
+//
+// For n up to 16, multiplication, inversion, and exponentiation use log and
+// antilog tables. For larger n, they use word-level arithmetic through a
+// Modulus. Methods on a Field do not modify it, so it is safe for concurrent
+// use.
+type Field struct {
+	// mod is the defining polynomial and n is its degree.
+	mod *Modulus
+	n   int
+	// exp and log are the antilog and log tables for small fields. exp holds
+	// two periods of powers of a generator so that sums of logs need no
+	// reduction. Both are nil for large fields.
+	exp, log []uint32
+	// tr has bit i set iff the trace of x^i is 1.
+	tr *big.Int
+	// sqrtx is the square root of x.
+	sqrtx *big.Int
+}
+
+// fieldTableMax is the largest degree for which fields use log tables.
+const fieldTableMax = 16
+
+// NewField creates the field GF(2)[x]/(p). Panics if p is not irreducible.
+func NewField(p *big.Int) *Field {
+	if !PolyIrreducible(p) {
+		panic(fmt.Sprintf("cannot make field from reducible polynomial %s", p.Text(2)))
+	}
+	F := Field{mod: NewModulus(p), n: PolyDeg(p)}
+	F.tr = traceMask(F.mod.Poly())
+	// sqrt(x) is x^(2^(n-1)), since squaring n times is the identity.
+	F.sqrtx = big.NewInt(2)
+	for i := 1; i < F.n; i++ {
+		F.mod.Sqr(F.sqrtx, F.sqrtx)
+	}
+	if F.n <= fieldTableMax {
+		F.tables()
+	}
+	return &F
+}
+
+// traceMask computes the traces of x^i for 0 <= i < n in GF(2)[x]/(p). The
+// trace of x^i is the ith power sum of the roots of p, and the generating
+// function of the power sums is t*q'(t)/q(t), where q is the reversal of p.
+func traceMask(p *big.Int) *big.Int {
+	n := PolyDeg(p)
+	q := polyRev(p, n)
+	s := polyInvSeries(q, n)
+	PolyMul(s, s, polyDeriv(q))
+	polyTrunc(s, s.Lsh(s, 1), n)
+	// The zeroth power sum is the trace of 1, which is n mod 2.
+	return s.SetBit(s, 0, uint(n&1))
+}
+
+// tables fills the log and antilog tables.
+func (F *Field) tables() {
+	N := uint32(1)<<uint(F.n) - 1
+	// Find a generator of the multiplicative group. Any element whose order
+	// is not a proper divisor of 2^n - 1 will do.
+	e := big.NewInt(int64(N))
+	g := new(big.Int)
+	var t, u big.Int
+search:
+	for k := int64(2); ; k++ {
+		g.SetInt64(k)
+		for _, q := range mersenneFactors(F.n) {
+			if F.mod.Exp(&u, g, t.Quo(e, q)).Cmp(oneP) == 0 {
+				continue search
+			}
+		}
+		break
+	}
+	if F.n == 1 {
+		// GF(2)* is trivial, so 1 generates it.
+		g.SetInt64(1)
+	}
+	F.exp = make([]uint32, 2*N)
+	F.log = make([]uint32, N+1)
+	a := big.NewInt(1)
+	for i := uint32(0); i < N; i++ {
+		v := uint32(a.Uint64())
+		F.exp[i], F.exp[i+N] = v, v
+		F.log[v] = i
+		F.mod.Mul(a, a, g)
+	}
+}
+
+// Poly returns the defining polynomial of the field. The returned value must
+// not be modified.
+func (F *Field) Poly() *big.Int {
+	return F.mod.Poly()
+}
+
+// Degree returns the degree n of the field over GF(2).
+func (F *Field) Degree() int {
+	return F.n
+}
+
+// Modulus returns the modulus used for arithmetic in the field.
+func (F *Field) Modulus() *Modulus {
+	return F.mod
+}
+
+// Reduce sets z to the element of the field represented by the polynomial x
+// and returns z.
+func (F *Field) Reduce(z, x *big.Int) *big.Int {
+	return F.mod.Reduce(z, x)
+}
+
+// Add sets z to x+y and returns z.
+func (F *Field) Add(z, x, y *big.Int) *big.Int {
+	z.Xor(x, y)
+	return F.mod.Reduce(z, z)
+}
+
+// Mul sets z to x*y and returns z.
+func (F *Field) Mul(z, x, y *big.Int) *big.Int {
+	if F.exp == nil {
+		return F.mod.Mul(z, x, y)
+	}
+	a, b := F.small(x), F.small(y)
+	if a == 0 || b == 0 {
+		return z.SetInt64(0)
+	}
+	return z.SetInt64(int64(F.exp[F.log[a]+F.log[b]]))
+}
+
+// Sqr sets z to x^2 and returns z.
+func (F *Field) Sqr(z, x *big.Int) *big.Int {
+	return F.mod.Sqr(z, x)
+}
+
+// Inv sets z to the multiplicative inverse of x and returns z. Panics if x is
+// zero.
+func (F *Field) Inv(z, x *big.Int) *big.Int {
+	if F.exp == nil {
+		if F.mod.Inverse(z, x) == nil {
+			panic("inverse of zero")
+		}
+		return z
+	}
+	a := F.small(x)
+	if a == 0 {
+		panic("inverse of zero")
+	}
+	N := uint32(len(F.log) - 1)
+	return z.SetInt64(int64(F.exp[N-F.log[a]]))
+}
+
+// Exp sets z to x^e and returns z. x^0 is 1 for all x. Panics if x is zero
+// and e is negative.
+func (F *Field) Exp(z, x, e *big.Int) *big.Int {
+	if F.exp == nil {
+		return F.mod.Exp(z, x, e)
+	}
+	a := F.small(x)
+	if e.Sign() == 0 {
+		return z.SetInt64(1)
+	}
+	if a == 0 {
+		if e.Sign() < 0 {
+			panic("inverse of zero")
+		}
+		return z.SetInt64(0)
+	}
+	N := uint32(len(F.log) - 1)
+	var k big.Int
+	k.Mul(e, k.SetInt64(int64(F.log[a])))
+	k.Mod(&k, big.NewInt(int64(N)))
+	return z.SetInt64(int64(F.exp[k.Uint64()]))
+}
+
+// Trace returns the absolute trace of x, x + x^2 + x^4 + ... + x^(2^(n-1)),
+// which is always 0 or 1. The trace is linear, so this takes only one pass
+// over the bits of x.
+func (F *Field) Trace(x *big.Int) uint {
+	var a big.Int
+	F.mod.Reduce(&a, x)
+	var p uint
+	t := F.tr.Bits()
+	for i, w := range a.Bits() {
+		if i < len(t) {
+			p ^= uint(bits.OnesCount(uint(w & t[i])))
+		}
+	}
+	return p & 1
+}
+
+// HalfTrace sets z to the half-trace of x, x + x^4 + x^16 + ... +
+// x^(2^(n-1)), and returns z. If the trace of x is zero, the half-trace is a
+// solution of z^2 + z = x. Panics if the degree of the field is even.
+func (F *Field) HalfTrace(z, x *big.Int) *big.Int {
+	if F.n%2 == 0 {
+		panic(fmt.Sprintf("half-trace is undefined in GF(2^%d)", F.n))
+	}
+	a := F.mod.Reduce(new(big.Int), x)
+	r := new(big.Int).Set(a)
+	for i := 0; i < F.n/2; i++ {
+		F.mod.Sqr(a, a)
+		F.mod.Sqr(a, a)
+		r.Xor(r, a)
+	}
+	return z.Set(r)
+}
+
+// Sqrt sets z to the unique square root of x and returns z. Writing x as
+// e(x)^2 + x*o(x)^2, the square root is e(x) + sqrt(x)*o(x), which costs one
+// multiplication by the precomputed square root of x.
+func (F *Field) Sqrt(z, x *big.Int) *big.Int {
+	var a big.Int
+	F.mod.Reduce(&a, x)
+	e, o := new(big.Int), new(big.Int)
+	for i := 0; i < a.BitLen(); i++ {
+		if a.Bit(i) != 0 {
+			if i%2 == 0 {
+				e.SetBit(e, i/2, 1)
+			} else {
+				o.SetBit(o, i/2, 1)
+			}
+		}
+	}
+	F.Mul(o, o, F.sqrtx)
+	return z.Xor(e, o)
+}
+
+// small returns the reduced value of x for a field with tables.
+func (F *Field) small(x *big.Int) uint32 {
+	if PolyDeg(x) < F.n {
+		return uint32(new(big.Int).Abs(x).Uint64())
+	}
+	var a big.Int
+	return uint32(F.mod.Reduce(&a, x).Uint64())
+}
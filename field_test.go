@@ -0,0 +1,106 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// testFields returns fields with and without log tables.
+func testFields() []*Field {
+	var r []*Field
+	for _, p := range [][]int{
+		{1, 0},
+		{4, 1, 0},
+		{8, 4, 3, 1, 0},
+		{13, 4, 3, 1, 0},
+		{16, 5, 3, 1, 0},
+		{17, 3, 0},
+		{31, 3, 0},
+		{64, 4, 3, 1, 0},
+		{127, 1, 0},
+	} {
+		f := new(big.Int)
+		for _, k := range p {
+			f.SetBit(f, k, 1)
+		}
+		r = append(r, NewField(f))
+	}
+	return r
+}
+
+// randElem returns a random element of F, zero only if nonzero is false.
+func randElem(r *rand.Rand, F *Field, nonzero bool) *big.Int {
+	lim := new(big.Int).Lsh(big.NewInt(1), uint(F.Degree()))
+	for {
+		x := new(big.Int).Rand(r, lim)
+		if !nonzero || x.Sign() != 0 {
+			return x
+		}
+	}
+}
+
+func TestFieldArith(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	one := big.NewInt(1)
+	for _, F := range testFields() {
+		n := F.Degree()
+		m := F.Modulus()
+		order := new(big.Int).Sub(new(big.Int).Lsh(one, uint(n)), one)
+		for i := 0; i < 50; i++ {
+			x, y := randElem(r, F, true), randElem(r, F, false)
+			if got, want := F.Mul(new(big.Int), x, y), m.Mul(new(big.Int), x, y); got.Cmp(want) != 0 {
+				t.Errorf("GF(2^%d): %v * %v = %v, want %v", n, x, y, got, want)
+			}
+			inv := F.Inv(new(big.Int), x)
+			if p := F.Mul(new(big.Int), x, inv); p.Cmp(one) != 0 {
+				t.Errorf("GF(2^%d): %v * %v^-1 = %v, want 1", n, x, x, p)
+			}
+			if p := F.Exp(new(big.Int), x, order); p.Cmp(one) != 0 {
+				t.Errorf("GF(2^%d): %v^(2^n - 1) = %v, want 1", n, x, p)
+			}
+			e := big.NewInt(r.Int63n(1 << 20))
+			if got, want := F.Exp(new(big.Int), x, e), m.Exp(new(big.Int), x, e); got.Cmp(want) != 0 {
+				t.Errorf("GF(2^%d): %v^%v = %v, want %v", n, x, e, got, want)
+			}
+			ne := new(big.Int).Neg(e)
+			if got, want := F.Exp(new(big.Int), x, ne), m.Exp(new(big.Int), inv, e); got.Cmp(want) != 0 {
+				t.Errorf("GF(2^%d): %v^-%v = %v, want %v", n, x, e, got, want)
+			}
+		}
+	}
+}
+
+func TestFieldSqrtTrace(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, F := range testFields() {
+		n := F.Degree()
+		if tr := F.Trace(big.NewInt(1)); tr != uint(n&1) {
+			t.Errorf("GF(2^%d): Tr(1) = %d", n, tr)
+		}
+		for i := 0; i < 50; i++ {
+			x, y := randElem(r, F, false), randElem(r, F, false)
+			s := F.Sqrt(new(big.Int), x)
+			if p := F.Sqr(new(big.Int), s); p.Cmp(x) != 0 {
+				t.Errorf("GF(2^%d): sqrt(%v)^2 = %v", n, x, p)
+			}
+			// The trace is the sum of the conjugates of x, and it is linear.
+			sum, c := new(big.Int).Set(x), new(big.Int).Set(x)
+			for k := 1; k < n; k++ {
+				sum.Xor(sum, F.Sqr(c, c))
+			}
+			if sum.Cmp(big.NewInt(int64(F.Trace(x)))) != 0 {
+				t.Errorf("GF(2^%d): Tr(%v) = %d, want %v", n, x, F.Trace(x), sum)
+			}
+			if F.Trace(new(big.Int).Xor(x, y)) != F.Trace(x)^F.Trace(y) {
+				t.Errorf("GF(2^%d): trace is not linear at %v, %v", n, x, y)
+			}
+			if n%2 == 1 && F.Trace(x) == 0 {
+				z := F.HalfTrace(new(big.Int), x)
+				if p := F.Sqr(new(big.Int), z); p.Xor(p, z).Cmp(x) != 0 {
+					t.Errorf("GF(2^%d): half-trace %v of %v does not solve z^2 + z = x", n, z, x)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package gof2
+
+import (
+	"math/big"
+)
+
+// Hermite computes the row Hermite normal form H of a matrix over GF(2)[x],
+// along with a unimodular matrix U such that U*A = H. H is in row echelon
+// form: the first non-zero element of each row, its pivot, lies strictly
+// right of that of the row above, every element above a pivot has lower
+// degree than the pivot, and zero rows come last. Since every non-zero
+// polynomial over GF(2) is monic, H is unique.
+func Hermite(A M) (H, U *PFM) {
+	rows, cols := A.Size()
+	a := denseRows(A)
+	u := eyeRows(rows)
+	var q, t big.Int
+	r := 0
+	for c := 0; c < cols && r < rows; c++ {
+		// Run Euclid's algorithm down column c until only the pivot remains.
+		for {
+			p, _ := minDegreeCol(a, r, c)
+			if p < 0 {
+				break
+			}
+			a[r], a[p] = a[p], a[r]
+			u[r], u[p] = u[p], u[r]
+			done := true
+			for i := r + 1; i < rows; i++ {
+				if a[i][c].Sign() == 0 {
+					continue
+				}
+				PolyDivMod(&q, nil, a[i][c], a[r][c])
+				addRowMul(a[i], a[r], &q, &t)
+				addRowMul(u[i], u[r], &q, &t)
+				if a[i][c].Sign() != 0 {
+					done = false
+				}
+			}
+			if done {
+				break
+			}
+		}
+		if a[r][c].Sign() == 0 {
+			continue
+		}
+		for i := 0; i < r; i++ {
+			if PolyDeg(a[i][c]) >= PolyDeg(a[r][c]) {
+				PolyDivMod(&q, nil, a[i][c], a[r][c])
+				addRowMul(a[i], a[r], &q, &t)
+				addRowMul(u[i], u[r], &q, &t)
+			}
+		}
+		r++
+	}
+	return pfmRows(a), pfmRows(u)
+}
+
+// WeakPopov computes a weak Popov form P of a matrix over GF(2)[x], along
+// with a unimodular matrix U such that U*A = P. The leading position of a
+// row is the rightmost column holding an element of the row's maximum
+// degree; in P, the leading positions of the non-zero rows are distinct.
+// P is row reduced, so its row degrees are minimal among all bases of the
+// row space of A, and it is computed using Mulders and Storjohann's simple
+// transformations.
+func WeakPopov(A M) (P, U *PFM) {
+	rows, _ := A.Size()
+	a := denseRows(A)
+	u := eyeRows(rows)
+	var s, t big.Int
+	// owner maps leading positions to the rows which hold them.
+	owner := make(map[int]int, rows)
+	for i := 0; i < rows; i++ {
+		for {
+			lp, d := leadingPos(a[i])
+			if lp < 0 {
+				break
+			}
+			j, ok := owner[lp]
+			if !ok {
+				owner[lp] = i
+				break
+			}
+			// Cancel the leading term of the row of higher degree using the
+			// other. That row then has either lower degree or a leading
+			// position further left, so it must be processed again.
+			_, e := leadingPos(a[j])
+			if d < e {
+				a[i], a[j] = a[j], a[i]
+				u[i], u[j] = u[j], u[i]
+				d, e = e, d
+			}
+			s.Lsh(oneP, uint(d-e))
+			addRowMul(a[i], a[j], &s, &t)
+			addRowMul(u[i], u[j], &s, &t)
+		}
+	}
+	return pfmRows(a), pfmRows(u)
+}
+
+// minDegreeCol returns the row of a non-zero element of least degree in
+// column c of a, from row r onward, or -1 if all such elements are zero.
+func minDegreeCol(a [][]*big.Int, r, c int) (int, int) {
+	bi, bd := -1, -1
+	for i := r; i < len(a); i++ {
+		if d := PolyDeg(a[i][c]); d >= 0 && (bd < 0 || d < bd) {
+			bi, bd = i, d
+		}
+	}
+	return bi, bd
+}
+
+// leadingPos returns the leading position and degree of a row, or -1, -1 if
+// the row is zero.
+func leadingPos(row []*big.Int) (int, int) {
+	lp, ld := -1, -1
+	for c, p := range row {
+		if d := PolyDeg(p); d >= 0 && d >= ld {
+			lp, ld = c, d
+		}
+	}
+	return lp, ld
+}
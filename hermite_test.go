@@ -0,0 +1,126 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randUnimodular creates a random n x n PFM with determinant 1 by applying
+// random row operations to the identity.
+func randUnimodular(r *rand.Rand, n int) *PFM {
+	u := eyeRows(n)
+	var t big.Int
+	for k := 0; k < 3*n; k++ {
+		i, j := r.Intn(n), r.Intn(n)
+		if i == j {
+			continue
+		}
+		if r.Intn(3) == 0 {
+			u[i], u[j] = u[j], u[i]
+		} else {
+			addRowMul(u[i], u[j], randPoly(r, r.Intn(3)), &t)
+		}
+	}
+	return pfmRows(u)
+}
+
+// rowDegrees returns the degrees of the rows of A, sorted.
+func rowDegrees(A M) []int {
+	rows, cols := A.Size()
+	d := make([]int, rows)
+	for i := range d {
+		d[i] = -1
+		for j := 1; j <= cols; j++ {
+			if e := PolyDeg(A.At(i+1, j)); e > d[i] {
+				d[i] = e
+			}
+		}
+	}
+	sort.Ints(d)
+	return d
+}
+
+func TestHermite(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	one := big.NewInt(1)
+	for _, sz := range [][2]int{{1, 1}, {3, 3}, {5, 5}, {2, 5}, {5, 2}, {4, 7}} {
+		for i := 0; i < 5; i++ {
+			A := randPFM(r, sz[0], sz[1], 3)
+			if i == 0 && sz[0] > 1 {
+				for j := 1; j <= sz[1]; j++ {
+					A.SetAt(sz[0], j, A.At(1, j))
+				}
+			}
+			H, U := Hermite(A)
+			if !sameElements(PMul(U, A), H) {
+				t.Errorf("U*A is not H for %dx%d matrix", sz[0], sz[1])
+			}
+			if d := Det(U); d.Cmp(one) != 0 {
+				t.Errorf("det U for %dx%d matrix is %v, want 1", sz[0], sz[1], d)
+			}
+			// Check the echelon form and the reduction above pivots.
+			last := 0
+			for row := 1; row <= sz[0]; row++ {
+				c := 1
+				for c <= sz[1] && H.At(row, c).Sign() == 0 {
+					c++
+				}
+				if c > sz[1] {
+					last = sz[1] + 1
+					continue
+				}
+				if c <= last {
+					t.Fatalf("pivot of row %d of H is in column %d, after %d", row, c, last)
+				}
+				last = c
+				for above := 1; above < row; above++ {
+					if PolyDeg(H.At(above, c)) >= PolyDeg(H.At(row, c)) {
+						t.Fatalf("element (%d,%d) of H is not reduced by its pivot", above, c)
+					}
+				}
+			}
+			// H is unique, so another basis of the same rows gives it.
+			H2, _ := Hermite(PMul(randUnimodular(r, sz[0]), A))
+			if !sameElements(H, H2) {
+				t.Errorf("Hermite forms of equivalent %dx%d matrices differ", sz[0], sz[1])
+			}
+		}
+	}
+}
+
+func TestWeakPopov(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	one := big.NewInt(1)
+	for _, sz := range [][2]int{{1, 1}, {3, 3}, {5, 5}, {2, 5}, {5, 2}, {4, 7}} {
+		for i := 0; i < 5; i++ {
+			A := randPFM(r, sz[0], sz[1], 3)
+			P, U := WeakPopov(A)
+			if !sameElements(PMul(U, A), P) {
+				t.Errorf("U*A is not P for %dx%d matrix", sz[0], sz[1])
+			}
+			if d := Det(U); d.Cmp(one) != 0 {
+				t.Errorf("det U for %dx%d matrix is %v, want 1", sz[0], sz[1], d)
+			}
+			seen := make(map[int]bool)
+			for _, row := range denseRows(P) {
+				lp, _ := leadingPos(row)
+				if lp >= 0 && seen[lp] {
+					t.Fatalf("leading position %d of P is repeated", lp)
+				}
+				seen[lp] = true
+			}
+			// Reduced bases of the same module have the same row degrees,
+			// even starting from a basis with inflated degrees.
+			Q, _ := WeakPopov(PMul(randUnimodular(r, sz[0]), A))
+			d, e := rowDegrees(P), rowDegrees(Q)
+			for k := range d {
+				if d[k] != e[k] {
+					t.Errorf("row degrees of equivalent %dx%d weak Popov forms are %v and %v", sz[0], sz[1], d, e)
+					break
+				}
+			}
+		}
+	}
+}
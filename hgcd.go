@@ -0,0 +1,152 @@
+package gof2
+
+import (
+	"math/big"
+)
+
+// PolyGCD sets z to the greatest common divisor of x and y and returns z. The
+// GCD of 0 and 0 is 0.
+func PolyGCD(z, x, y *big.Int) *big.Int {
+	g, _ := gcdMat(x, y, false)
+	return z.Set(g)
+}
+
+// PolyHalfGCD computes the greatest common divisor of a and b along with a
+// 2x2 cofactor matrix U such that U*(a, b)^T = (g, 0)^T. U has determinant 1,
+// so it is invertible over GF(2)[x], and its first row gives the Bézout
+// coefficients of a and b. Large inputs are reduced with the half-GCD
+// algorithm, which runs in O(M(n) log n) time where M(n) is the cost of
+// multiplication.
+func PolyHalfGCD(a, b *big.Int) (g *big.Int, U *PFM) {
+	g, R := gcdMat(a, b, true)
+	return g, R.pfm()
+}
+
+// HGCD computes the half-GCD reduction of a and b, which must satisfy
+// deg(a) > deg(b). The result is a 2x2 matrix R such that R*(a, b)^T =
+// (c, d)^T, where c and d are consecutive remainders in the Euclidean
+// remainder sequence of a and b with deg(c) >= ceil(deg(a)/2) > deg(d). This
+// is the building block for rational reconstruction and subquadratic GCDs.
+// Panics if deg(a) <= deg(b).
+func HGCD(a, b *big.Int) *PFM {
+	if PolyDeg(a) <= PolyDeg(b) {
+		panic("HGCD requires deg(a) > deg(b)")
+	}
+	return hgcd(new(big.Int).Abs(a), new(big.Int).Abs(b)).pfm()
+}
+
+// hgcdCutoff is the degree below which the half-GCD uses the classical
+// Euclidean algorithm.
+const hgcdCutoff = 1024
+
+// pmat is a 2x2 polynomial matrix stored in row-major order, used to
+// accumulate steps of the Euclidean algorithm.
+type pmat [4]*big.Int
+
+// pmatEye returns the 2x2 identity matrix.
+func pmatEye() pmat {
+	return pmat{big.NewInt(1), new(big.Int), new(big.Int), big.NewInt(1)}
+}
+
+// mul returns the product m*n.
+func (m pmat) mul(n pmat) pmat {
+	var t big.Int
+	r := pmat{new(big.Int), new(big.Int), new(big.Int), new(big.Int)}
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			PolyMul(r[2*i+j], m[2*i], n[j])
+			r[2*i+j].Xor(r[2*i+j], PolyMul(&t, m[2*i+1], n[2+j]))
+		}
+	}
+	return r
+}
+
+// apply returns m*(a, b)^T.
+func (m pmat) apply(a, b *big.Int) (c, d *big.Int) {
+	var t big.Int
+	c = PolyMul(new(big.Int), m[0], a)
+	c.Xor(c, PolyMul(&t, m[1], b))
+	d = PolyMul(new(big.Int), m[2], a)
+	d.Xor(d, PolyMul(&t, m[3], b))
+	return c, d
+}
+
+// step returns the product of the Euclidean step matrix [[0, 1], [1, q]] and
+// m, which takes (a, b) to (b, a+q*b).
+func (m pmat) step(q *big.Int) pmat {
+	r0 := PolyMul(new(big.Int), q, m[2])
+	r1 := PolyMul(new(big.Int), q, m[3])
+	return pmat{m[2], m[3], r0.Xor(r0, m[0]), r1.Xor(r1, m[1])}
+}
+
+// pfm converts m to a PFM.
+func (m pmat) pfm() *PFM {
+	U := NewPFull(2, 2)
+	// PFM elements are column-major.
+	U.v[0], U.v[1], U.v[2], U.v[3] = m[0], m[2], m[1], m[3]
+	return U
+}
+
+// hgcd returns the half-GCD matrix of a and b, which must be non-negative
+// with deg(a) > deg(b).
+func hgcd(a, b *big.Int) pmat {
+	n := PolyDeg(a)
+	m := (n + 1) / 2
+	if PolyDeg(b) < m {
+		return pmatEye()
+	}
+	if n < hgcdCutoff {
+		R := pmatEye()
+		for PolyDeg(b) >= m {
+			q, r := PolyDivMod(nil, nil, a, b)
+			R = R.step(q)
+			a, b = b, r
+		}
+		return R
+	}
+	// The quotients of the top halves of a and b agree with those of a and b
+	// until the remainders are about half the degree of the top halves.
+	R := hgcd(new(big.Int).Rsh(a, uint(m)), new(big.Int).Rsh(b, uint(m)))
+	c, d := R.apply(a, b)
+	if PolyDeg(d) < m {
+		return R
+	}
+	q, r := PolyDivMod(nil, nil, c, d)
+	R = R.step(q)
+	c, d = d, r
+	if PolyDeg(d) < m {
+		return R
+	}
+	k := uint(2*m - PolyDeg(c))
+	S := hgcd(new(big.Int).Rsh(c, k), new(big.Int).Rsh(d, k))
+	return S.mul(R)
+}
+
+// gcdMat computes the GCD of a and b. If wantR is true, it also returns the
+// cofactor matrix taking (a, b) to (g, 0).
+func gcdMat(a, b *big.Int, wantR bool) (*big.Int, pmat) {
+	a, b = new(big.Int).Abs(a), new(big.Int).Abs(b)
+	R := pmatEye()
+	if PolyDeg(a) < PolyDeg(b) {
+		a, b = b, a
+		R = pmat{new(big.Int), big.NewInt(1), big.NewInt(1), new(big.Int)}
+	}
+	for b.Sign() != 0 {
+		if PolyDeg(a) >= hgcdCutoff && PolyDeg(a) > PolyDeg(b) {
+			S := hgcd(a, b)
+			a, b = S.apply(a, b)
+			if wantR {
+				R = S.mul(R)
+			}
+			if b.Sign() == 0 {
+				break
+			}
+		}
+		q, r := PolyDivMod(nil, nil, a, b)
+		if wantR {
+			R = R.step(q)
+		}
+		a, b = b, r
+	}
+	return a, R
+}
@@ -0,0 +1,108 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randPoly returns a random polynomial of degree exactly d, or zero if d < 0.
+func randPoly(r *rand.Rand, d int) *big.Int {
+	if d < 0 {
+		return new(big.Int)
+	}
+	p := new(big.Int).Rand(r, new(big.Int).Lsh(big.NewInt(1), uint(d)))
+	return p.SetBit(p, d, 1)
+}
+
+// euclid computes the GCD of a and b by repeated PolyMod.
+func euclid(a, b *big.Int) *big.Int {
+	a, b = new(big.Int).Set(a), new(big.Int).Set(b)
+	for b.Sign() != 0 {
+		a, b = b, PolyMod(a, a, b)
+	}
+	return a
+}
+
+// applyPFM returns U*(a, b)^T for a 2x2 PFM U.
+func applyPFM(U *PFM, a, b *big.Int) (c, d *big.Int) {
+	var t big.Int
+	c = PolyMul(new(big.Int), U.At(1, 1), a)
+	c.Xor(c, PolyMul(&t, U.At(1, 2), b))
+	d = PolyMul(new(big.Int), U.At(2, 1), a)
+	d.Xor(d, PolyMul(&t, U.At(2, 2), b))
+	return c, d
+}
+
+// detPFM returns the determinant of a 2x2 PFM.
+func detPFM(U *PFM) *big.Int {
+	var t big.Int
+	d := PolyMul(new(big.Int), U.At(1, 1), U.At(2, 2))
+	return d.Xor(d, PolyMul(&t, U.At(1, 2), U.At(2, 1)))
+}
+
+// gcdCases returns pairs of polynomials with a common factor, with degrees on
+// both sides of hgcdCutoff.
+func gcdCases(r *rand.Rand) [][2]*big.Int {
+	var cases [][2]*big.Int
+	for _, d := range [][3]int{{0, 5, 3}, {2, 40, 40}, {10, 300, 299}, {0, 1000, 900}, {50, 3000, 2500}, {700, 4000, 100}, {3, 2*hgcdCutoff + 5, 2 * hgcdCutoff}} {
+		g := randPoly(r, d[0])
+		a := PolyMul(new(big.Int), g, randPoly(r, d[1]))
+		b := PolyMul(new(big.Int), g, randPoly(r, d[2]))
+		cases = append(cases, [2]*big.Int{a, b}, [2]*big.Int{b, a})
+	}
+	cases = append(cases, [2]*big.Int{new(big.Int), randPoly(r, 20)}, [2]*big.Int{randPoly(r, 20), new(big.Int)})
+	return cases
+}
+
+func TestPolyGCD(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, c := range gcdCases(r) {
+		a, b := c[0], c[1]
+		want := euclid(a, b)
+		if got := PolyGCD(new(big.Int), a, b); got.Cmp(want) != 0 {
+			t.Errorf("PolyGCD of degrees %d, %d has degree %d, want %d", PolyDeg(a), PolyDeg(b), PolyDeg(got), PolyDeg(want))
+		}
+	}
+	if g := PolyGCD(new(big.Int), new(big.Int), new(big.Int)); g.Sign() != 0 {
+		t.Errorf("PolyGCD(0, 0) = %v, want 0", g)
+	}
+}
+
+func TestPolyHalfGCD(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, c := range gcdCases(r) {
+		a, b := c[0], c[1]
+		g, U := PolyHalfGCD(a, b)
+		if want := euclid(a, b); g.Cmp(want) != 0 {
+			t.Errorf("GCD of degrees %d, %d has degree %d, want %d", PolyDeg(a), PolyDeg(b), PolyDeg(g), PolyDeg(want))
+		}
+		x, y := applyPFM(U, a, b)
+		if x.Cmp(g) != 0 || y.Sign() != 0 {
+			t.Errorf("U*(a, b) for degrees %d, %d is not (g, 0)", PolyDeg(a), PolyDeg(b))
+		}
+		if d := detPFM(U); d.Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("det U for degrees %d, %d is %v, want 1", PolyDeg(a), PolyDeg(b), d)
+		}
+	}
+}
+
+func TestHGCD(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, d := range [][2]int{{1, 0}, {7, 3}, {100, 99}, {hgcdCutoff - 1, hgcdCutoff - 2}, {hgcdCutoff, 10}, {3000, 2999}, {5001, 4000}} {
+		a, b := randPoly(r, d[0]), randPoly(r, d[1])
+		R := HGCD(a, b)
+		c, e := applyPFM(R, a, b)
+		m := (PolyDeg(a) + 1) / 2
+		if PolyDeg(c) < m || PolyDeg(e) >= m {
+			t.Errorf("HGCD of degrees %d, %d reduces to degrees %d, %d around %d", d[0], d[1], PolyDeg(c), PolyDeg(e), m)
+		}
+		if det := detPFM(R); det.Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("det R for degrees %d, %d is %v, want 1", d[0], d[1], det)
+		}
+		// Consecutive remainders have the same GCD as a and b.
+		if euclid(c, e).Cmp(euclid(a, b)) != 0 {
+			t.Errorf("HGCD of degrees %d, %d changes the GCD", d[0], d[1])
+		}
+	}
+}
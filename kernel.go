@@ -0,0 +1,130 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// ApproximantBasis computes a shifted minimal approximant basis of order k
+// for an m x n matrix F over GF(2)[x]. The result P is an m x m matrix whose
+// rows form a basis of all row vectors p such that p*F = 0 mod x^k, and which
+// is reduced with respect to the shift s, in that it minimizes the shifted
+// row degrees max_j(deg(p_j) + s[j]). If s is nil, the shift is zero. The
+// shifted row degrees of P are also returned.
+//
+// This uses the iterative M-Basis algorithm, handling one power of x at a
+// time, so it takes O(k m^2 n) polynomial operations. Panics if k is negative
+// or if s has the wrong length.
+func ApproximantBasis(F M, k int, s []int) (P *PFM, deg []int) {
+	m, n := F.Size()
+	if k < 0 {
+		panic(fmt.Sprintf("cannot compute approximant basis of negative order %d", k))
+	}
+	if s != nil && len(s) != m {
+		panic(fmt.Sprintf("cannot use shift of length %d with %dx%d matrix", len(s), m, n))
+	}
+	deg = make([]int, m)
+	copy(deg, s)
+	p := eyeRows(m)
+	r := denseRows(F)
+	mbasis(p, r, deg, k)
+	return pfmRows(p), deg
+}
+
+// KernelBasis computes a minimal basis of the left kernel of a matrix F over
+// GF(2)[x], i.e. of all row vectors p such that p*F = 0. The rows of the
+// result form a basis whose row degrees are minimal, in a sense shifted by
+// the row degrees of F. If the kernel is trivial, the result is nil.
+//
+// The kernel is found as the rows of an approximant basis of order high
+// enough that approximating zero implies being zero.
+func KernelBasis(F M) *PFM {
+	m, _ := F.Size()
+	r := denseRows(F)
+	// With s the row degrees of F, any p such that p*F = 0 mod x^k with
+	// shifted degree less than k has p*F of degree less than k, so p*F = 0.
+	// Conversely, the shifted degrees of a minimal kernel basis sum to at
+	// most the sum of s, so that order suffices to find all of it.
+	s := make([]int, m)
+	k := 1
+	for i, row := range r {
+		for _, p := range row {
+			if d := PolyDeg(p); d > s[i] {
+				s[i] = d
+			}
+		}
+		k += s[i]
+	}
+	deg := make([]int, m)
+	copy(deg, s)
+	p := eyeRows(m)
+	mbasis(p, r, deg, k)
+	var ker [][]*big.Int
+	for i, row := range p {
+		// The residual of row i is now exactly row i times F.
+		zero := true
+		for _, q := range r[i] {
+			if q.Sign() != 0 {
+				zero = false
+				break
+			}
+		}
+		if zero && deg[i] < k {
+			ker = append(ker, row)
+		}
+	}
+	if ker == nil {
+		return nil
+	}
+	return pfmRows(ker)
+}
+
+// mbasis transforms p, initially a basis with shifted row degrees deg, into a
+// minimal approximant basis of order k for F, where r holds p*F on entry. On
+// return, r holds the new p*F, and deg holds the new shifted row degrees.
+func mbasis(p, r [][]*big.Int, deg []int, k int) {
+	m := len(p)
+	order := make([]int, m)
+	var t big.Int
+	for o := 0; o < k; o++ {
+		// Eliminate coefficient o of the residual, combining rows only into
+		// rows of equal or greater shifted degree, and multiply the rows that
+		// remain non-zero by x. Each row is reduced by previous pivots, in
+		// order of increasing degree, until it is zero or has a new pivot.
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return deg[order[i]] < deg[order[j]] })
+		var pivots []int
+		var cols []int
+		for _, i := range order {
+			for x, j := range pivots {
+				if r[i][cols[x]].Bit(o) != 0 {
+					addRowMul(r[i], r[j], oneP, &t)
+					addRowMul(p[i], p[j], oneP, &t)
+				}
+			}
+			c := -1
+			for y, q := range r[i] {
+				if q.Bit(o) != 0 {
+					c = y
+					break
+				}
+			}
+			if c >= 0 {
+				pivots = append(pivots, i)
+				cols = append(cols, c)
+			}
+		}
+		for _, i := range pivots {
+			for _, q := range r[i] {
+				q.Lsh(q, 1)
+			}
+			for _, q := range p[i] {
+				q.Lsh(q, 1)
+			}
+			deg[i]++
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// isZeroM reports whether every element of A is zero.
+func isZeroM(A M) bool {
+	rows, cols := A.Size()
+	for i := 1; i <= rows; i++ {
+		for j := 1; j <= cols; j++ {
+			if A.At(i, j).Sign() != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestApproximantBasis(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sz := range [][2]int{{1, 1}, {3, 1}, {4, 2}, {5, 3}} {
+		for _, k := range []int{0, 1, 4, 9} {
+			F := randPFM(r, sz[0], sz[1], 4)
+			s := make([]int, sz[0])
+			for i := range s {
+				s[i] = r.Intn(3)
+			}
+			P, deg := ApproximantBasis(F, k, s)
+			if !isZeroM(PTrunc(PMul(P, F), k)) {
+				t.Errorf("P*F is not zero mod x^%d for %dx%d matrix", k, sz[0], sz[1])
+			}
+			// Each step multiplies one row by x, so the determinant is a
+			// power of x matching the increase in shifted degrees.
+			n := 0
+			for i := range deg {
+				n += deg[i] - s[i]
+			}
+			want := new(big.Int).Lsh(big.NewInt(1), uint(n))
+			if d := Det(P); d.Cmp(want) != 0 {
+				t.Errorf("det P of order %d for %dx%d matrix is %v, want x^%d", k, sz[0], sz[1], d, n)
+			}
+		}
+	}
+}
+
+func TestKernelBasis(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, sz := range [][2]int{{1, 1}, {2, 1}, {4, 2}, {5, 3}, {3, 3}, {6, 2}} {
+		for i := 0; i < 5; i++ {
+			F := randPFM(r, sz[0], sz[1], 3)
+			if i == 0 && sz[1] > 1 {
+				// Reduce the rank with a repeated column.
+				for j := 1; j <= sz[0]; j++ {
+					F.SetAt(j, sz[1], F.At(j, 1))
+				}
+			}
+			inv, _, _ := Smith(F, false)
+			rank := len(inv)
+			K := KernelBasis(F)
+			if K == nil {
+				if rank != sz[0] {
+					t.Errorf("no kernel for %dx%d matrix of rank %d", sz[0], sz[1], rank)
+				}
+				continue
+			}
+			if kr, _ := K.Size(); kr != sz[0]-rank {
+				t.Errorf("kernel of %dx%d matrix of rank %d has %d rows", sz[0], sz[1], rank, kr)
+			}
+			if !isZeroM(PMul(K, F)) {
+				t.Errorf("K*F is not zero for %dx%d matrix", sz[0], sz[1])
+			}
+			if d, _, _ := Smith(K, false); len(d) != sz[0]-rank {
+				t.Errorf("kernel basis of %dx%d matrix has rank %d, want %d", sz[0], sz[1], len(d), sz[0]-rank)
+			}
+		}
+	}
+}
@@ -0,0 +1,741 @@
+package gof2
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// maxLarge is the maximum dimension of the large matrix types. Sparse large
+// matrices pack coordinates into the halves of a uint64.
+const maxLarge = math.MaxUint32
+
+// maxInt is the largest int, which bounds the number of elements of full
+// matrices.
+const maxInt = int(^uint(0) >> 1)
+
+// LSM is a sparse matrix of binary elements like SM, but with dimensions up to
+// 4294967295 instead of 65535.
+type LSM struct {
+	// r and c are the size of the matrix.
+	r, c int
+	// v is the map of zero-based coordinates to elements. The column occupies
+	// the upper 32 bits and the row the lower ones.
+	v map[uint64]uint8
+}
+
+// NewLSparse creates a zero matrix of the given size. Panics if either size is
+// non-positive or greater than 4294967295.
+func NewLSparse(rows, cols int) *LSM {
+	checkLarge(rows, cols, false)
+	return &LSM{r: rows, c: cols, v: make(map[uint64]uint8)}
+}
+
+// LSparse converts any type of binary matrix to a new large sparse matrix.
+// Panics if the argument is a polynomial matrix with any element having degree
+// higher than one, or if m is too large. Types LSM, LFM, SM, FM, PSM, LPSM, DM,
+// CSR, CSC, BM, TM, Toep, Circ, I, Z, R, S, Comp, and Perm are special-cased,
+// Sum and Product are evaluated first, and V is read from its viewed matrix.
+// All other types are filled in O(mn) time.
+func LSparse(m M) *LSM {
+	m = unwrapAdaptive(m)
+	rows, cols := m.Size()
+	checkLarge(rows, cols, false)
+	B := LSM{rows, cols, make(map[uint64]uint8)}
+	switch A := m.(type) {
+	case *LSM:
+		for k, v := range A.v {
+			if v != 0 {
+				B.v[k] = v
+			}
+		}
+	case *SM:
+		for k, v := range A.v {
+			if v != 0 {
+				B.v[lkey(int(k&0xffff), int(k>>16))] = 1
+			}
+		}
+	case *PSM:
+		for k, v := range A.v {
+			if check01(v) != 0 {
+				B.v[lkey(int(k&0xffff), int(k>>16))] = 1
+			}
+		}
+	case *LPSM:
+		for k, v := range A.v {
+			if check01(v) != 0 {
+				B.v[k] = 1
+			}
+		}
+	case *FM:
+		forMatrixBits(A.v, rows, cols, func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case *LFM:
+		forMatrixBits(A.v, rows, cols, func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case *DM:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case *CSR:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case *CSC:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case Comp:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case Perm:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case *BM:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case *TM:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case Toep:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case Circ:
+		A.forEach(func(r, c int) { B.v[lkey(r, c)] = 1 })
+	case Sum:
+		return LSparse(A.Eval())
+	case Product:
+		return LSparse(A.Eval())
+	case V:
+		return LSparse(A.binary())
+	case I:
+		for k := 0; k < rows && k < cols; k++ {
+			B.v[lkey(k, k)] = 1
+		}
+	case Z:
+		// do nothing
+	case R:
+		for i := 0; i < rows; i++ {
+			r := (i + A.n) % rows
+			if r < 0 {
+				r += rows
+			}
+			B.v[lkey(r, i)] = 1
+		}
+	case S:
+		for i := 0; i < rows; i++ {
+			if r := i + A.n; r >= 0 && r < rows {
+				B.v[lkey(r, i)] = 1
+			}
+		}
+	default:
+		for c := 0; c < cols; c++ {
+			for r := 0; r < rows; r++ {
+				if check01(A.At(r+1, c+1)) != 0 {
+					B.v[lkey(r, c)] = 1
+				}
+			}
+		}
+	}
+	return &B
+}
+
+// Size returns the number of rows and columns in the matrix.
+func (sm *LSM) Size() (rows, cols int) {
+	return sm.r, sm.c
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be
+// modified.
+func (sm *LSM) At(r, c int) *big.Int {
+	if sm.v[sm.index(r, c)] != 0 {
+		return oneP
+	}
+	return zeroP
+}
+
+// SetAt sets the value at a one-based row and column index to the given
+// polynomial. Panics if the index is out of bounds or if p is not 0 or 1.
+func (sm *LSM) SetAt(r, c int, p *big.Int) {
+	k := sm.index(r, c)
+	if check01(p) != 0 {
+		sm.v[k] = 1
+	} else {
+		delete(sm.v, k)
+	}
+}
+
+// AddAt adds to the element at the given one-based row and column. Panics if
+// the index is out of bounds or if p is not 0 or 1.
+func (sm *LSM) AddAt(r, c int, p *big.Int) *big.Int {
+	k := sm.index(r, c)
+	if sm.v[k] ^= check01(p); sm.v[k] == 0 {
+		delete(sm.v, k)
+		return zeroP
+	}
+	return oneP
+}
+
+// MulAt multiplies the element at the given one-based row and column. Panics
+// if the index is out of bounds or if p is not 0 or 1.
+func (sm *LSM) MulAt(r, c int, p *big.Int) *big.Int {
+	k := sm.index(r, c)
+	if sm.v[k] &= check01(p); sm.v[k] == 0 {
+		delete(sm.v, k)
+		return zeroP
+	}
+	return oneP
+}
+
+// index panics if the given row or column indices are out of bounds and
+// returns the corresponding sparse coordinate otherwise.
+func (sm *LSM) index(r, c int) uint64 {
+	if r--; r < 0 || r >= sm.r {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r+1, sm.r, sm.c))
+	}
+	if c--; c < 0 || c >= sm.c {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c+1, sm.r, sm.c))
+	}
+	return lkey(r, c)
+}
+
+// LFM is a full matrix of binary elements like FM, but with dimensions up to
+// 4294967295 instead of 65535, as long as the number of elements fits in an
+// int. Like FM, the bit vector has a bit set past the end of the matrix data.
+type LFM struct {
+	r, c int
+	v    *big.Int
+}
+
+// NewLFull creates a zero matrix of the given size. Panics if either size is
+// non-positive or greater than 4294967295, or if the matrix has too many
+// elements.
+func NewLFull(rows, cols int) *LFM {
+	checkLarge(rows, cols, true)
+	fm := LFM{r: rows, c: cols, v: new(big.Int)}
+	fm.v.SetBit(fm.v, rows*cols, 1)
+	return &fm
+}
+
+// LFull converts any type of binary matrix to a new large full matrix. Panics
+// if the argument is a polynomial matrix with any element having degree higher
+// than one, or if m is too large. Types LSM, LFM, SM, FM, DM, BM, TM, Toep,
+// Circ, I, Z, R, S, Comp, and Perm are special-cased, Sum and Product are
+// evaluated first, and V is read from its viewed matrix; all other types are
+// filled in O(mn) time.
+func LFull(m M) *LFM {
+	m = unwrapAdaptive(m)
+	rows, cols := m.Size()
+	checkLarge(rows, cols, true)
+	B := NewLFull(rows, cols)
+	switch A := m.(type) {
+	case *LSM:
+		for k, v := range A.v {
+			if v != 0 {
+				B.v.SetBit(B.v, int(k>>32)*rows+int(k&0xffffffff), 1)
+			}
+		}
+	case *SM:
+		for k, v := range A.v {
+			if v != 0 {
+				B.v.SetBit(B.v, int(k>>16)*rows+int(k&0xffff), 1)
+			}
+		}
+	case *FM:
+		if A.v.Sign() != 0 {
+			B.v.Set(A.v)
+		}
+	case *LFM:
+		B.v.Set(A.v)
+	case *DM:
+		B.v.SetBits(denseBits(A))
+	case Comp:
+		A.forEach(func(r, c int) { B.v.SetBit(B.v, c*rows+r, 1) })
+	case Perm:
+		A.forEach(func(r, c int) { B.v.SetBit(B.v, c*rows+r, 1) })
+	case *BM:
+		A.forEach(func(r, c int) { B.v.SetBit(B.v, c*rows+r, 1) })
+	case *TM:
+		B.v.SetBits(denseBits(A.dense()))
+	case Toep:
+		B.v.SetBits(columnBits(rows, cols, A.column))
+	case Circ:
+		B.v.SetBits(columnBits(rows, cols, A.column))
+	case Sum:
+		return LFull(A.Eval())
+	case Product:
+		return LFull(A.Eval())
+	case V:
+		return LFull(A.binary())
+	case I:
+		for k := 0; k < rows && k < cols; k++ {
+			B.v.SetBit(B.v, k*rows+k, 1)
+		}
+	case Z:
+		// do nothing
+	case R:
+		for k := 0; k < rows; k++ {
+			r := (k + A.n) % rows
+			if r < 0 {
+				r += rows
+			}
+			B.v.SetBit(B.v, k*rows+r, 1)
+		}
+	case S:
+		for k := 0; k < rows; k++ {
+			if r := k + A.n; r >= 0 && r < rows {
+				B.v.SetBit(B.v, k*rows+r, 1)
+			}
+		}
+	default:
+		for c := 0; c < cols; c++ {
+			for r := 0; r < rows; r++ {
+				if check01(A.At(r+1, c+1)) != 0 {
+					B.v.SetBit(B.v, c*rows+r, 1)
+				}
+			}
+		}
+	}
+	return B
+}
+
+// Size returns the size of the matrix.
+func (fm *LFM) Size() (rows, cols int) {
+	return fm.r, fm.c
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be
+// modified.
+func (fm *LFM) At(r, c int) *big.Int {
+	return to01(fm.v.Bit(fm.index(r, c)) != 0)
+}
+
+// SetAt sets the value at a one-based row and column index to the given
+// polynomial. Panics if the index is out of bounds or if p is not 0 or 1.
+func (fm *LFM) SetAt(r, c int, p *big.Int) {
+	fm.v.SetBit(fm.v, fm.index(r, c), uint(check01(p)))
+}
+
+// AddAt adds to the element at the given one-based row and column. Panics if
+// the index is out of bounds or if p is not 0 or 1.
+func (fm *LFM) AddAt(r, c int, p *big.Int) *big.Int {
+	k := fm.index(r, c)
+	v := fm.v.Bit(k) ^ uint(check01(p))
+	fm.v.SetBit(fm.v, k, v)
+	return to01(v != 0)
+}
+
+// MulAt multiplies the element at the given one-based row and column. Panics
+// if the index is out of bounds or if p is not 0 or 1.
+func (fm *LFM) MulAt(r, c int, p *big.Int) *big.Int {
+	k := fm.index(r, c)
+	v := fm.v.Bit(k) & uint(check01(p))
+	fm.v.SetBit(fm.v, k, v)
+	return to01(v != 0)
+}
+
+// index panics if the given row or column indices are out of bounds and
+// returns the corresponding bit vector coordinate otherwise. The bit vector
+// is column-major.
+func (fm *LFM) index(r, c int) int {
+	if r--; r < 0 || r >= fm.r {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r+1, fm.r, fm.c))
+	}
+	if c--; c < 0 || c >= fm.c {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c+1, fm.r, fm.c))
+	}
+	return c*fm.r + r
+}
+
+// LPSM is a sparse polynomial matrix like PSM, but with dimensions up to
+// 4294967295 instead of 65535.
+type LPSM struct {
+	// r and c are the size of the matrix.
+	r, c int
+	// v is the map of coordinates to polynomials. The column occupies the
+	// upper 32 bits and the row the lower ones.
+	v map[uint64]*big.Int
+}
+
+// NewLPSparse creates a zero matrix of the given size. Panics if either size
+// is non-positive or greater than 4294967295.
+func NewLPSparse(rows, cols int) *LPSM {
+	checkLarge(rows, cols, false)
+	return &LPSM{r: rows, c: cols, v: make(map[uint64]*big.Int)}
+}
+
+// LPSparse converts any type of matrix to a large sparse polynomial matrix.
+// Panics if the argument is too large. Types LPSM, PSM, V, and all types
+// handled specially by LSparse are special-cased. All other types are filled
+// in O(mn) time.
+func LPSparse(m M) *LPSM {
+	m = unwrapAdaptive(m)
+	rows, cols := m.Size()
+	checkLarge(rows, cols, false)
+	B := LPSM{rows, cols, make(map[uint64]*big.Int)}
+	switch A := m.(type) {
+	case V:
+		return A.poly()
+	case *LPSM:
+		for k, v := range A.v {
+			if v.Sign() != 0 {
+				B.v[k] = new(big.Int).Set(v)
+			}
+		}
+	case *PSM:
+		for k, v := range A.v {
+			if v.Sign() != 0 {
+				B.v[lkey(int(k&0xffff), int(k>>16))] = new(big.Int).Set(v)
+			}
+		}
+	case *LSM, *SM, *FM, *LFM, *DM, *CSR, *CSC, Comp, Perm, *BM, *TM, Toep, Circ, Sum, Product, I, Z, R,
+		S:
+		for k := range LSparse(A).v {
+			B.v[k] = big.NewInt(1)
+		}
+	default:
+		for c := 0; c < cols; c++ {
+			for r := 0; r < rows; r++ {
+				q := A.At(r+1, c+1)
+				if q.Sign() != 0 {
+					B.v[lkey(r, c)] = new(big.Int).Set(q)
+				}
+			}
+		}
+	}
+	return &B
+}
+
+// Size returns the size of the matrix.
+func (A *LPSM) Size() (rows, cols int) {
+	return A.r, A.c
+}
+
+// At returns the polynomial at the given one-based index. The returned element
+// is a reference if and only if it is nonzero.
+func (A *LPSM) At(r, c int) *big.Int {
+	p := A.v[A.index(r, c)]
+	if p == nil || p.Sign() == 0 {
+		return new(big.Int)
+	}
+	return p
+}
+
+// SetAt sets the polynomial at the given one-based index. The polynomial is
+// not copied.
+func (A *LPSM) SetAt(r, c int, p *big.Int) {
+	k := A.index(r, c)
+	if p.Sign() == 0 {
+		delete(A.v, k)
+	} else {
+		A.v[k] = p
+	}
+}
+
+// AddAt adds a polynomial to that in the given index. The returned value is
+// always a reference, even if it is zero.
+func (A *LPSM) AddAt(r, c int, p *big.Int) *big.Int {
+	k := A.index(r, c)
+	q, ok := A.v[k]
+	if !ok {
+		q = new(big.Int)
+		A.v[k] = q
+	}
+	return q.Xor(q, p)
+}
+
+// MulAt multiplies (i.e. convolves coefficients of) the polynomial in a given
+// index by another. The returned value is always a reference, even if it is
+// zero.
+func (A *LPSM) MulAt(r, c int, p *big.Int) *big.Int {
+	k := A.index(r, c)
+	q, ok := A.v[k]
+	if !ok {
+		q = new(big.Int)
+		A.v[k] = q
+		return q
+	}
+	return PolyMul(q, q, p)
+}
+
+// index panics if the given row or column indices are out of bounds and
+// returns the corresponding sparse coordinate otherwise.
+func (A *LPSM) index(r, c int) uint64 {
+	if r--; r < 0 || r >= A.r {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r+1, A.r, A.c))
+	}
+	if c--; c < 0 || c >= A.c {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c+1, A.r, A.c))
+	}
+	return lkey(r, c)
+}
+
+// LPFM is a full polynomial matrix like PFM, but with dimensions up to
+// 4294967295 instead of 65535, as long as the number of elements fits in an
+// int.
+type LPFM struct {
+	r, c int
+	v    []*big.Int
+}
+
+// NewLPFull creates a zero matrix of the given size. This allocates all
+// elements. Panics if either size is non-positive or greater than 4294967295,
+// or if the matrix has too many elements.
+func NewLPFull(rows, cols int) *LPFM {
+	checkLarge(rows, cols, true)
+	v := make([]*big.Int, rows*cols)
+	for i := range v {
+		v[i] = new(big.Int)
+	}
+	return &LPFM{r: rows, c: cols, v: v}
+}
+
+// LPFull converts any type of matrix to a large full polynomial matrix.
+// Panics if the argument is too large. There are no special cases; converting
+// any matrix results in m*n calls to m.At().
+func LPFull(m M) *LPFM {
+	rows, cols := m.Size()
+	checkLarge(rows, cols, true)
+	B := LPFM{rows, cols, make([]*big.Int, rows*cols)}
+	for c := 0; c < cols; c++ {
+		for r := 0; r < rows; r++ {
+			B.v[c*rows+r] = new(big.Int).Set(m.At(r+1, c+1))
+		}
+	}
+	return &B
+}
+
+// Size returns the size of the matrix.
+func (A *LPFM) Size() (rows, cols int) {
+	return A.r, A.c
+}
+
+// At returns the element at the given one-based index. The returned element is
+// always a reference.
+func (A *LPFM) At(r, c int) *big.Int {
+	return A.v[A.index(r, c)]
+}
+
+// SetAt sets an element. The polynomial is not copied.
+func (A *LPFM) SetAt(r, c int, p *big.Int) {
+	A.v[A.index(r, c)] = p
+}
+
+// AddAt adds a polynomial to that in the given index. The returned value is a
+// reference.
+func (A *LPFM) AddAt(r, c int, p *big.Int) *big.Int {
+	k := A.index(r, c)
+	return A.v[k].Xor(A.v[k], p)
+}
+
+// MulAt multiplies (i.e. convolves coefficients of) the polynomial in a given
+// index by another. The returned value is a reference.
+func (A *LPFM) MulAt(r, c int, p *big.Int) *big.Int {
+	k := A.index(r, c)
+	return PolyMul(A.v[k], A.v[k], p)
+}
+
+// index panics if the given row or column indices are out of bounds and
+// returns the corresponding vector coordinate otherwise. The vector is
+// column-major.
+func (A *LPFM) index(r, c int) int {
+	if r--; r < 0 || r >= A.r {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r+1, A.r, A.c))
+	}
+	if c--; c < 0 || c >= A.c {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c+1, A.r, A.c))
+	}
+	return c*A.r + r
+}
+
+// checkLarge panics if rows or cols is not a valid large matrix dimension, or
+// if full is true and the matrix has too many elements to index with an int.
+func checkLarge(rows, cols int, full bool) {
+	if rows <= 0 || cols <= 0 {
+		panic(fmt.Sprintf("cannot make %dx%d matrix: size must be positive", rows, cols))
+	}
+	if uint64(rows) > maxLarge || uint64(cols) > maxLarge {
+		panic(fmt.Sprintf("cannot make %dx%d matrix: maximum dimension is %d", rows, cols, uint64(maxLarge)))
+	}
+	if full && rows > maxInt/cols {
+		panic(fmt.Sprintf("cannot make %dx%d matrix: too many elements", rows, cols))
+	}
+}
+
+// isLarge returns whether m is one of the large matrix types.
+func isLarge(m M) bool {
+	switch m.(type) {
+	case *LSM, *LFM, *LPSM, *LPFM:
+		return true
+	}
+	return false
+}
+
+// lkey returns the large sparse coordinate of a zero-based row and column.
+func lkey(r, c int) uint64 {
+	return uint64(c)<<32 | uint64(r)
+}
+
+// forMatrixBits calls f with the zero-based row and column of each set bit in
+// the column-major bit vector of a full matrix, excluding the sentinel bit.
+func forMatrixBits(v *big.Int, rows, cols int, f func(r, c int)) {
+	n := rows * cols
+	forBits(v, func(b int) {
+		if b < n {
+			f(b%rows, b/rows)
+		}
+	})
+}
+
+// fMulLSS multiplies two large sparse matrices into a new LSM.
+func fMulLSS(A, B *LSM) *LSM {
+	C := NewLSparse(A.r, B.c)
+	// Index the columns of the nonzero elements of B by row.
+	rows := make(map[uint64][]uint64, len(B.v))
+	for k, b := range B.v {
+		if b != 0 {
+			rows[k&0xffffffff] = append(rows[k&0xffffffff], k&^0xffffffff)
+		}
+	}
+	for j, a := range A.v {
+		if a == 0 {
+			continue
+		}
+		for _, c := range rows[j>>32] {
+			k := c | j&0xffffffff
+			if C.v[k] ^= 1; C.v[k] == 0 {
+				delete(C.v, k)
+			}
+		}
+	}
+	return C
+}
+
+// fMulLFull multiplies two large full matrices into a new LFM. Each column of
+// the product is the sum of the columns of A selected by the corresponding
+// column of B.
+func fMulLFull(A, B *LFM) *LFM {
+	ar, ac := A.Size()
+	_, bc := B.Size()
+	const w = bits.UintSize
+	n := ar*bc + 1
+	v := make([]big.Word, (n+w-1)/w)
+	col := make([]big.Word, (ar+w-1)/w)
+	src := A.v.Bits()
+	bv := B.v.Bits()
+	sel := make([]big.Word, (ac+w-1)/w)
+	for c := 0; c < bc; c++ {
+		for i := range col {
+			col[i] = 0
+		}
+		for i := range sel {
+			sel[i] = 0
+		}
+		xorBitRange(sel, bv, c*ac)
+		if ac%w != 0 {
+			sel[len(sel)-1] &= 1<<uint(ac%w) - 1
+		}
+		for i, s := range sel {
+			for s != 0 {
+				k := i*w + bits.TrailingZeros(uint(s))
+				xorBitRange(col, src, k*ar)
+				s &= s - 1
+			}
+		}
+		if ar%w != 0 {
+			col[len(col)-1] &= 1<<uint(ar%w) - 1
+		}
+		orBitRange(v, col, c*ar)
+	}
+	v[ar*bc/w] |= 1 << uint(ar*bc%w)
+	return &LFM{ar, bc, new(big.Int).SetBits(v)}
+}
+
+// orBitRange sets in dst the bits of src shifted up by off bits.
+func orBitRange(dst, src []big.Word, off int) {
+	const w = bits.UintSize
+	q, s := off/w, uint(off%w)
+	for i, x := range src {
+		if x == 0 {
+			continue
+		}
+		dst[q+i] |= x << s
+		if s != 0 && q+i+1 < len(dst) {
+			dst[q+i+1] |= x >> (w - s)
+		}
+	}
+}
+
+// pMulLSS multiplies two large sparse polynomial matrices into a new LPSM.
+func pMulLSS(A, B *LPSM) *LPSM {
+	C := NewLPSparse(A.r, B.c)
+	// Index the columns of the nonzero elements of B by row.
+	rows := make(map[uint64][]uint64, len(B.v))
+	for k := range B.v {
+		rows[k&0xffffffff] = append(rows[k&0xffffffff], k)
+	}
+	var t big.Int
+	for j, a := range A.v {
+		for _, k := range rows[j>>32] {
+			i := k&^0xffffffff | j&0xffffffff
+			q, ok := C.v[i]
+			if !ok {
+				q = new(big.Int)
+				C.v[i] = q
+			}
+			q.Xor(q, PolyMul(&t, a, B.v[k]))
+		}
+	}
+	for k, q := range C.v {
+		if q.Sign() == 0 {
+			delete(C.v, k)
+		}
+	}
+	return C
+}
+
+// pMulLSX multiplies a large sparse polynomial matrix by any matrix into a
+// new LPFM.
+func pMulLSX(A *LPSM, B M) *LPFM {
+	_, bc := B.Size()
+	C := NewLPFull(A.r, bc)
+	var t big.Int
+	for j, a := range A.v {
+		r, c := int(j&0xffffffff), int(j>>32)
+		for i := 0; i < bc; i++ {
+			q := C.v[i*A.r+r]
+			q.Xor(q, PolyMul(&t, a, B.At(c+1, i+1)))
+		}
+	}
+	return C
+}
+
+// pMulLXS multiplies any matrix by a large sparse polynomial matrix into a
+// new LPFM.
+func pMulLXS(A M, B *LPSM) *LPFM {
+	ar, _ := A.Size()
+	C := NewLPFull(ar, B.c)
+	var t big.Int
+	for j, b := range B.v {
+		r, c := int(j&0xffffffff), int(j>>32)
+		for i := 0; i < ar; i++ {
+			q := C.v[c*ar+i]
+			q.Xor(q, PolyMul(&t, A.At(i+1, r+1), b))
+		}
+	}
+	return C
+}
+
+// lCoeffs splits a large polynomial matrix into LSM coefficients, or LFM if
+// A is not sparse, as for Coeffs.
+func lCoeffs(A M) []M {
+	rows, cols := A.Size()
+	var s []*LSM
+	for k, p := range LPSparse(A).v {
+		for len(s) < p.BitLen() {
+			s = append(s, NewLSparse(rows, cols))
+		}
+		forBits(p, func(i int) {
+			s[i].v[k] = 1
+		})
+	}
+	c := make([]M, len(s))
+	for i, C := range s {
+		if isSparse(A) {
+			c[i] = C
+		} else {
+			c[i] = LFull(C)
+		}
+	}
+	return c
+}
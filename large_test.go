@@ -0,0 +1,156 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestLargeTypesSmall(t *testing.T) {
+	// Large types with small sizes must agree with the small types.
+	r := rand.New(rand.NewSource(1))
+	for _, sz := range [][3]int{{1, 1, 1}, {5, 9, 3}, {64, 64, 64}, {70, 130, 65}} {
+		A, B := randSparse(r, sz[0], sz[1], sz[0]*sz[1]/4), randSparse(r, sz[1], sz[2], sz[1]*sz[2]/4)
+		want := FMul(A, B)
+		for _, X := range []M{LSparse(A), LFull(A)} {
+			if !sameElements(X, A) || !sameElements(Full(X), A) || !sameElements(Sparse(X), A) {
+				t.Errorf("%T of size %dx%d disagrees with SM", X, sz[0], sz[1])
+			}
+			for _, Y := range []M{LSparse(B), LFull(B), B, Full(B)} {
+				C := FMul(X, Y)
+				if !isLarge(C) || !sameElements(C, want) {
+					t.Errorf("%T * %T of size %dx%dx%d is wrong or has type %T", X, Y, sz[0], sz[1], sz[2], C)
+				}
+			}
+			checkMulVec(t, r, "large", X)
+		}
+		P, Q := randPSM(r, sz[0], sz[1], sz[0], 4), randPFM(r, sz[1], sz[2], 4)
+		want = PMul(P, Q)
+		for _, X := range []M{LPSparse(P), LPFull(P)} {
+			if !sameElements(X, P) {
+				t.Errorf("%T of size %dx%d disagrees with PSM", X, sz[0], sz[1])
+			}
+			for _, Y := range []M{LPSparse(Q), LPFull(Q), Q} {
+				C := PMul(X, Y)
+				if !isLarge(C) || !sameElements(C, want) {
+					t.Errorf("PMul of %T * %T of size %dx%dx%d is wrong or has type %T", X, Y, sz[0], sz[1], sz[2], C)
+				}
+			}
+		}
+	}
+}
+
+func TestLargeProducts(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	// The inner dimension alone is too large for the small types.
+	if C := FMul(Eye(3, largeN), Eye(largeN, 3)); !sameElements(C, Eye(3, 3)) {
+		t.Errorf("I(3x%d) * I(%dx3) is not I", largeN, largeN)
+	}
+	if C := PMul(Eye(3, largeN), Eye(largeN, 3)); !isLarge(C) || !sameElements(C, Eye(3, 3)) {
+		t.Errorf("PMul(I(3x%d), I(%dx3)) is not I or has type %T", largeN, largeN, C)
+	}
+	A := NewLPSparse(largeN, 3)
+	for i := 0; i < 10; i++ {
+		A.SetAt(r.Intn(largeN)+1, r.Intn(3)+1, randPoly(r, r.Intn(5)))
+	}
+	B := randPFM(r, 3, 2, 4)
+	for _, Y := range []M{LPSparse(B), B} {
+		C := PMul(A, Y)
+		_, sparse := Y.(*LPSM)
+		if _, ok := C.(*LPSM); ok != sparse {
+			t.Errorf("PMul of LPSM and %T has type %T", Y, C)
+		}
+		for k := range A.v {
+			i := int(k&0xffffffff) + 1
+			for c := 1; c <= 2; c++ {
+				want := new(big.Int)
+				for l := 1; l <= 3; l++ {
+					want.Xor(want, PolyMul(new(big.Int), A.At(i, l), B.At(l, c)))
+				}
+				if got := C.At(i, c); got.Cmp(want) != 0 {
+					t.Errorf("element (%d,%d) of product with %T is %v, want %v", i, c, Y, got, want)
+				}
+			}
+		}
+	}
+	// Multiplying on the other side exercises the transposed paths.
+	At := NewLPSparse(3, largeN)
+	for k, p := range A.v {
+		At.SetAt(int(k>>32)+1, int(k&0xffffffff)+1, p)
+	}
+	Bt := NewPFull(2, 3)
+	for i := 1; i <= 3; i++ {
+		for j := 1; j <= 2; j++ {
+			Bt.SetAt(j, i, B.At(i, j))
+		}
+	}
+	C := PMul(LPSparse(Bt), At)
+	D := PMul(Bt, At)
+	for k := range A.v {
+		i := int(k&0xffffffff) + 1
+		for c := 1; c <= 2; c++ {
+			want := new(big.Int)
+			for l := 1; l <= 3; l++ {
+				want.Xor(want, PolyMul(new(big.Int), B.At(l, c), At.At(l, i)))
+			}
+			if C.At(c, i).Cmp(want) != 0 || D.At(c, i).Cmp(want) != 0 {
+				t.Errorf("element (%d,%d) of transposed product is %v and %v, want %v", c, i, C.At(c, i), D.At(c, i), want)
+			}
+		}
+	}
+}
+
+func TestLargeCoeffs(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	A := NewLPSparse(largeN, 3)
+	for i := 0; i < 20; i++ {
+		A.SetAt(r.Intn(largeN)+1, r.Intn(3)+1, randPoly(r, r.Intn(6)))
+	}
+	c := Coeffs(A)
+	for k, C := range c {
+		if _, ok := C.(*LSM); !ok {
+			t.Fatalf("coefficient %d of LPSM has type %T", k, C)
+		}
+	}
+	B := FromCoeffs(c)
+	if _, ok := B.(*LPSM); !ok || !sameLPSM(A, B.(*LPSM)) {
+		t.Errorf("LPSM does not round-trip through its coefficients (got %T)", B)
+	}
+	T := PTrunc(A, 2)
+	P, ok := T.(*LPSM)
+	if !ok {
+		t.Fatalf("PTrunc of LPSM has type %T", T)
+	}
+	for k, p := range A.v {
+		i, j := int(k&0xffffffff)+1, int(k>>32)+1
+		if want := polyTrunc(new(big.Int), p, 2); P.At(i, j).Cmp(want) != 0 {
+			t.Errorf("element (%d,%d) mod x^2 is %v, want %v", i, j, P.At(i, j), want)
+		}
+	}
+	if len(P.v) > len(A.v) {
+		t.Errorf("PTrunc of LPSM has %d elements, more than the original %d", len(P.v), len(A.v))
+	}
+	// Small large types keep their full or sparse kind.
+	Q := LPFull(randPFM(r, 4, 5, 5))
+	for _, C := range Coeffs(Q) {
+		if _, ok := C.(*LFM); !ok {
+			t.Fatalf("coefficient of LPFM has type %T", C)
+		}
+	}
+	if R := FromCoeffs(Coeffs(Q)); !sameElements(R, Q) {
+		t.Errorf("LPFM does not round-trip through its coefficients (got %T)", R)
+	}
+}
+
+// sameLPSM reports whether two LPSMs have the same size and elements.
+func sameLPSM(A, B *LPSM) bool {
+	if A.r != B.r || A.c != B.c || len(A.v) != len(B.v) {
+		return false
+	}
+	for k, p := range A.v {
+		if q, ok := B.v[k]; !ok || p.Cmp(q) != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -65,9 +65,12 @@ func NewSparse(rows, cols int) *SM {
 
 // Sparse converts any type of binary matrix to a new sparse matrix. Panics if
 // the argument is a polynomial matrix with any element having degree higher
-// than one, or if m is too large. Types SM, FM, I, Z, R, and S are
-// special-cased. All other types are filled in O(mn) time.
+// than one, or if m is too large. Types SM, FM, LSM, LFM, DM, CSR, CSC, BM, TM,
+// Toep, Circ, I, Z, R, S, Comp, and Perm are special-cased, Sum and Product
+// are evaluated first, and V is read from its viewed matrix. All other types
+// are filled in O(mn) time.
 func Sparse(m M) *SM {
+	m = unwrapAdaptive(m)
 	rows, cols := m.Size()
 	if rows > 65535 || cols > 65535 {
 		panic(fmt.Sprintf("cannot make %dx%d matrix: maximum dimension is 65535", rows, cols))
@@ -96,15 +99,47 @@ func Sparse(m M) *SM {
 			}
 			k += bits.UintSize
 		}
+	case *LSM:
+		for k, v := range A.v {
+			if v != 0 {
+				B.v[uint32(k>>32)<<16|uint32(k&0xffff)] = 1
+			}
+		}
+	case *LFM:
+		forMatrixBits(A.v, rows, cols, func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case *DM:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case *CSR:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case *CSC:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case Comp:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case Perm:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case *BM:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case *TM:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case Toep:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case Circ:
+		A.forEach(func(r, c int) { B.v[uint32(c)<<16|uint32(r)] = 1 })
+	case Sum:
+		return Sparse(A.Eval())
+	case Product:
+		return Sparse(A.Eval())
+	case V:
+		return Sparse(A.binary())
 	case I:
-		for k := 0; k < rows; k++ {
+		for k := 0; k < rows && k < cols; k++ {
 			B.v[uint32(k)*0x00010001] = 1
 		}
 	case Z:
 		// do nothing
 	case R:
 		for i := 0; i < rows; i++ {
-			r := i + A.n%rows
+			r := (i + A.n) % rows
 			if r < 0 {
 				r += rows
 			}
@@ -211,9 +246,12 @@ func NewFull(rows, cols int) *FM {
 
 // Full converts any type of binary matrix to a new full matrix. Panics if
 // the argument is a polynomial matrix with any element having degree higher
-// than one, or if m is too large. Types SM, FM, I, Z, R, and S are
-// special-cased; all other types are filled in O(mn) time.
+// than one, or if m is too large. Types SM, FM, LSM, LFM, DM, CSR, CSC, BM, TM,
+// Toep, Circ, I, Z, R, S, Comp, and Perm are special-cased, Sum and Product
+// are evaluated first, and V is read from its viewed matrix; all other types
+// are filled in O(mn) time.
 func Full(m M) *FM {
+	m = unwrapAdaptive(m)
 	rows, cols := m.Size()
 	if rows > 65535 || cols > 65535 {
 		panic(fmt.Sprintf("cannot make %dx%d matrix: maximum dimension is 65535", rows, cols))
@@ -231,16 +269,54 @@ func Full(m M) *FM {
 		}
 	case *FM:
 		B.v.Set(A.v)
+	case *LSM:
+		B.v.SetBit(B.v, rows*cols, 1)
+		for k, v := range A.v {
+			if v != 0 {
+				B.v.SetBit(B.v, int(k>>32)*rows+int(k&0xffffffff), 1)
+			}
+		}
+	case *LFM:
+		B.v.Set(A.v)
+	case *DM:
+		B.v.SetBits(denseBits(A))
+	case *CSR:
+		B.v.SetBit(B.v, rows*cols, 1)
+		A.forEach(func(r, c int) { B.v.SetBit(B.v, c*rows+r, 1) })
+	case *CSC:
+		B.v.SetBit(B.v, rows*cols, 1)
+		A.forEach(func(r, c int) { B.v.SetBit(B.v, c*rows+r, 1) })
+	case Comp:
+		B.v.SetBit(B.v, rows*cols, 1)
+		A.forEach(func(r, c int) { B.v.SetBit(B.v, c*rows+r, 1) })
+	case Perm:
+		B.v.SetBit(B.v, rows*cols, 1)
+		A.forEach(func(r, c int) { B.v.SetBit(B.v, c*rows+r, 1) })
+	case *BM:
+		B.v.SetBit(B.v, rows*cols, 1)
+		A.forEach(func(r, c int) { B.v.SetBit(B.v, c*rows+r, 1) })
+	case *TM:
+		B.v.SetBits(denseBits(A.dense()))
+	case Toep:
+		B.v.SetBits(columnBits(rows, cols, A.column))
+	case Circ:
+		B.v.SetBits(columnBits(rows, cols, A.column))
+	case Sum:
+		return Full(A.Eval())
+	case Product:
+		return Full(A.Eval())
+	case V:
+		return Full(A.binary())
 	case I:
 		B.v.SetBit(B.v, rows*cols, 1)
-		for k := 0; k < rows; k++ {
+		for k := 0; k < rows && k < cols; k++ {
 			B.v.SetBit(B.v, k*rows+k, 1)
 		}
 	case Z:
 		// do nothing
 	case R:
 		for k := 0; k < rows; k++ {
-			r := k + A.n%rows
+			r := (k + A.n) % rows
 			if r < 0 {
 				r += rows
 			}
@@ -253,7 +329,7 @@ func Full(m M) *FM {
 			}
 		} else {
 			for i := 0; i < rows+A.n; i++ {
-				B.v.SetBit(B.v, (i+A.n)*rows+i, 1)
+				B.v.SetBit(B.v, (i-A.n)*rows+i, 1)
 			}
 		}
 	default:
@@ -0,0 +1,215 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Modulus holds precomputed values for repeated arithmetic modulo a fixed
+// polynomial f. If f has at most five terms, as with trinomials and
+// pentanomials, reduction XORs shifted copies of the low terms in time linear
+// in the size of the operand. Otherwise, it uses Barrett reduction with a
+// precomputed inverse of the reversal of f, costing two multiplications.
+//
+// Methods on a Modulus do not modify it, so it is safe for concurrent use.
+// Arguments to its methods need not be reduced, and results always are.
+type Modulus struct {
+	// f is the modulus and n is its degree.
+	f *big.Int
+	n int
+	// tail is the list of exponents of the terms of f other than x^n, in
+	// decreasing order, or nil if f has too many terms to reduce sparsely.
+	tail []int
+	// mu is floor(x^2n / f), used for Barrett reduction.
+	mu *big.Int
+}
+
+// maxSparseWeight is the largest number of terms a modulus may have to use
+// sparse reduction.
+const maxSparseWeight = 5
+
+// NewModulus precomputes values for arithmetic modulo f. Panics if f has
+// degree less than one.
+func NewModulus(f *big.Int) *Modulus {
+	n := PolyDeg(f)
+	if n < 1 {
+		panic(fmt.Sprintf("cannot use polynomial %s as a modulus: degree must be positive", f.Text(2)))
+	}
+	m := Modulus{f: new(big.Int).Abs(f), n: n}
+	if w := weight(m.f); w <= maxSparseWeight {
+		m.tail = make([]int, 0, w-1)
+		for k := n - 1; k >= 0; k-- {
+			if m.f.Bit(k) != 0 {
+				m.tail = append(m.tail, k)
+			}
+		}
+		return &m
+	}
+	// The reversal of floor(x^2n / f) is the inverse of the reversal of f
+	// modulo x^(n+1).
+	m.mu = polyRev(polyInvSeries(polyRev(m.f, n), n+1), n)
+	return &m
+}
+
+// Poly returns the modulus polynomial. The returned value must not be
+// modified.
+func (m *Modulus) Poly() *big.Int {
+	return m.f
+}
+
+// Deg returns the degree of the modulus.
+func (m *Modulus) Deg() int {
+	return m.n
+}
+
+// Reduce sets z to x modulo f and returns z.
+func (m *Modulus) Reduce(z, x *big.Int) *big.Int {
+	if PolyDeg(x) < m.n {
+		return z.Abs(x)
+	}
+	if m.tail != nil {
+		r := append([]big.Word(nil), x.Bits()...)
+		return z.SetBits(reduceSparse(r, m.n, m.tail))
+	}
+	if PolyDeg(x) >= 2*m.n {
+		return PolyMod(z, x, m.f)
+	}
+	// Barrett reduction: the quotient is floor(floor(x / x^n) * mu / x^n),
+	// with no correction needed over GF(2).
+	q := new(big.Int).Rsh(new(big.Int).Abs(x), uint(m.n))
+	PolyMul(q, q, m.mu)
+	q.Rsh(q, uint(m.n))
+	PolyMul(q, q, m.f)
+	return z.Xor(q, new(big.Int).Abs(x))
+}
+
+// Mul sets z to x*y modulo f and returns z.
+func (m *Modulus) Mul(z, x, y *big.Int) *big.Int {
+	var a, b big.Int
+	m.Reduce(&a, x)
+	m.Reduce(&b, y)
+	return m.Reduce(z, PolyMul(&a, &a, &b))
+}
+
+// Sqr sets z to x^2 modulo f and returns z.
+func (m *Modulus) Sqr(z, x *big.Int) *big.Int {
+	var a big.Int
+	m.Reduce(&a, x)
+	return m.Reduce(z, PolySqr(&a, &a))
+}
+
+// Exp sets z to x^e modulo f and returns z. If e is negative, x must be
+// invertible modulo f; otherwise, Exp panics. x^0 is 1 for any x.
+func (m *Modulus) Exp(z, x, e *big.Int) *big.Int {
+	b := new(big.Int)
+	if e.Sign() < 0 {
+		if m.Inverse(b, x) == nil {
+			panic(fmt.Sprintf("polynomial %s is not invertible modulo %s", x.Text(2), m.f.Text(2)))
+		}
+		e = new(big.Int).Neg(e)
+	} else {
+		m.Reduce(b, x)
+	}
+	r := big.NewInt(1)
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		m.Sqr(r, r)
+		if e.Bit(i) != 0 {
+			m.Mul(r, r, b)
+		}
+	}
+	return z.Set(r)
+}
+
+// Inverse sets z to the inverse of x modulo f and returns z. If x is not
+// invertible, i.e. it shares a factor with f, then z is unchanged and the
+// return value is nil.
+func (m *Modulus) Inverse(z, x *big.Int) *big.Int {
+	var a big.Int
+	m.Reduce(&a, x)
+	g, U := PolyHalfGCD(&a, m.f)
+	if g.Cmp(oneP) != 0 {
+		return nil
+	}
+	return m.Reduce(z, U.At(1, 1))
+}
+
+// reduceSparse reduces r in place modulo x^n plus the terms listed in tail,
+// returning the normalized result.
+func reduceSparse(r []big.Word, n int, tail []int) []big.Word {
+	const w = bits.UintSize
+	var t [1]big.Word
+	for i := len(r) - 1; i >= 0 && i*w+w > n; i-- {
+		// mask selects the bits of r[i] at or above x^n.
+		mask := ^big.Word(0)
+		if i*w < n {
+			mask <<= uint(n - i*w)
+		}
+		for r[i]&mask != 0 {
+			t[0] = r[i] & mask
+			r[i] ^= t[0]
+			for _, k := range tail {
+				s := i*w - (n - k)
+				if s >= 0 {
+					xorShifted(r, t[:], s)
+				} else {
+					// The set bits of t are all at least -s, so the shift
+					// loses nothing.
+					u := [1]big.Word{t[0] >> uint(-s)}
+					xorShifted(r, u[:], 0)
+				}
+			}
+		}
+	}
+	return normWords(r)
+}
+
+// polyRev returns the reversal of p as a polynomial of degree n, i.e.
+// x^n * p(1/x). Terms of p above x^n are discarded.
+func polyRev(p *big.Int, n int) *big.Int {
+	const w = bits.UintSize
+	nw := n/w + 1
+	v := make([]big.Word, nw)
+	copy(v, p.Bits())
+	if n%w != w-1 {
+		// Discard terms above x^n.
+		v[nw-1] &= 1<<uint(n%w+1) - 1
+	}
+	for i, j := 0, nw-1; i <= j; i, j = i+1, j-1 {
+		v[i], v[j] = big.Word(bits.Reverse(uint(v[j]))), big.Word(bits.Reverse(uint(v[i])))
+	}
+	r := new(big.Int).SetBits(v)
+	return r.Rsh(r, uint(nw*w-n-1))
+}
+
+// polyInvSeries returns the inverse of p modulo x^k using Newton iteration.
+// p must have a constant term of 1.
+func polyInvSeries(p *big.Int, k int) *big.Int {
+	g := big.NewInt(1)
+	var t big.Int
+	for prec := 1; prec < k; {
+		prec *= 2
+		if prec > k {
+			prec = k
+		}
+		// Over GF(2), the Newton step g(2 - pg) is p*g^2.
+		polyTrunc(&t, p, prec)
+		PolySqr(g, g)
+		PolyMul(g, g, &t)
+		polyTrunc(g, g, prec)
+	}
+	return polyTrunc(g, g, k)
+}
+
+// polyTrunc sets z to p modulo x^k and returns z.
+func polyTrunc(z, p *big.Int, k int) *big.Int {
+	if p.BitLen() <= k {
+		return z.Abs(p)
+	}
+	const w = bits.UintSize
+	v := append([]big.Word(nil), p.Bits()[:(k+w-1)/w]...)
+	if k%w != 0 {
+		v[len(v)-1] &= 1<<uint(k%w) - 1
+	}
+	return z.SetBits(v)
+}
@@ -0,0 +1,99 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// testModuli returns moduli covering sparse and Barrett reduction.
+func testModuli(r *rand.Rand) []*big.Int {
+	return []*big.Int{
+		big.NewInt(0x3),   // x + 1
+		big.NewInt(0x13),  // x^4 + x + 1
+		big.NewInt(0x1f),  // x^4 + x^3 + x^2 + x + 1
+		big.NewInt(0x11b), // x^8 + x^4 + x^3 + x + 1
+		big.NewInt(0xff),  // x^7 + ... + 1, too dense for sparse reduction
+		new(big.Int).SetBit(big.NewInt(0x9), 127, 1),
+		randPoly(r, 64),
+		randPoly(r, 300),
+	}
+}
+
+// expNaive computes x^e mod f by repeated multiplication.
+func expNaive(x *big.Int, e int, f *big.Int) *big.Int {
+	z := PolyMod(new(big.Int), big.NewInt(1), f)
+	for i := 0; i < e; i++ {
+		PolyMod(z, PolyMul(z, z, x), f)
+	}
+	return z
+}
+
+func TestModulusArith(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, f := range testModuli(r) {
+		m := NewModulus(f)
+		n := PolyDeg(f)
+		for i := 0; i < 20; i++ {
+			x, y := randPoly(r, r.Intn(3*n)), randPoly(r, r.Intn(2*n))
+			if got, want := m.Reduce(new(big.Int), x), PolyMod(new(big.Int), x, f); got.Cmp(want) != 0 {
+				t.Errorf("Reduce(%v) mod %v = %v, want %v", x, f, got, want)
+			}
+			want := PolyMod(new(big.Int), PolyMul(new(big.Int), x, y), f)
+			if got := m.Mul(new(big.Int), x, y); got.Cmp(want) != 0 {
+				t.Errorf("Mul(%v, %v) mod %v = %v, want %v", x, y, f, got, want)
+			}
+			want = PolyMod(new(big.Int), PolyMul(new(big.Int), x, x), f)
+			if got := m.Sqr(new(big.Int), x); got.Cmp(want) != 0 {
+				t.Errorf("Sqr(%v) mod %v = %v, want %v", x, f, got, want)
+			}
+		}
+	}
+}
+
+func TestModulusExp(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, f := range testModuli(r) {
+		m := NewModulus(f)
+		for i := 0; i < 10; i++ {
+			x := randPoly(r, r.Intn(2*PolyDeg(f)))
+			for _, e := range []int{0, 1, 2, 3, 17, 64, 255} {
+				want := expNaive(x, e, f)
+				if got := m.Exp(new(big.Int), x, big.NewInt(int64(e))); got.Cmp(want) != 0 {
+					t.Errorf("%v^%d mod %v = %v, want %v", x, e, f, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestModulusInverse(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	one := big.NewInt(1)
+	for _, f := range testModuli(r) {
+		m := NewModulus(f)
+		for i := 0; i < 10; i++ {
+			x := randPoly(r, r.Intn(2*PolyDeg(f)))
+			var inv big.Int
+			if PolyGCD(new(big.Int), x, f).Cmp(one) != 0 {
+				if m.Inverse(&inv, x) != nil {
+					t.Errorf("%v has inverse %v mod %v but shares a factor with it", x, &inv, f)
+				}
+				continue
+			}
+			if m.Inverse(&inv, x) == nil {
+				t.Errorf("%v has no inverse mod %v", x, f)
+				continue
+			}
+			if p := m.Mul(new(big.Int), x, &inv); p.Cmp(one) != 0 {
+				t.Errorf("%v * %v mod %v = %v, want 1", x, &inv, f, p)
+			}
+			// Negative powers are powers of the inverse.
+			e := big.NewInt(int64(r.Intn(100) + 1))
+			got := m.Exp(new(big.Int), x, new(big.Int).Neg(e))
+			if want := m.Exp(new(big.Int), &inv, e); got.Cmp(want) != 0 {
+				t.Errorf("%v^-%v mod %v = %v, want %v", x, e, f, got, want)
+			}
+		}
+	}
+}
@@ -2,10 +2,33 @@ package gof2
 
 import (
 	"fmt"
+	"math/big"
+	"math/bits"
 )
 
-// FMul multiplies two matrices in GF(2). If either argument is sparse, the
-// result is SM. If either argument is Z, the result is Z. Panics if the inner
+// FMul multiplies two matrices in GF(2). The first rule below that matches the
+// arguments decides how the product is computed and its type:
+//
+//	AM                   product of the storage, as a new AM
+//	Sum or Product       the other argument multiplied by each term or factor
+//	V                    product of a copy of the view
+//	Z                    Z
+//	CSR or CSC           CSC if both are CSC, CSR otherwise
+//	DM, neither sparse   DM
+//	BM*BM, TM*TM, Circ*Circ, Perm*Perm
+//	                     the same type; TMs must have the same orientation
+//	large type, or a dimension over 65535
+//	                     LSM if a Perm multiplies a sparse matrix, or if
+//	                     either is sparse and neither is BM, TM, Toep, or
+//	                     Circ; LFM otherwise
+//	Comp or Perm         SM if the other is sparse, FM otherwise
+//	BM, Toep, or Circ    FM
+//	sparse               SM
+//	otherwise            FM
+//
+// The sparse types are SM, PSM, LSM, LPSM, CSR, CSC, I, R, S, Comp, and Perm.
+// Products with Perm, BM, Toep, and Circ use the structure of that type at
+// any size, and products with Comp at sizes up to 65535. Panics if the inner
 // dimensions of the matrices are not equal or if any element is not 0 or 1.
 func FMul(A, B M) M {
 	ar, ac := A.Size()
@@ -13,19 +36,101 @@ func FMul(A, B M) M {
 	if ac != br {
 		panic(fmt.Sprintf("inner dimension mismatch: %dx%d * %dx%d", ar, ac, br, bc))
 	}
+	_, aa := A.(*AM)
+	_, ba := B.(*AM)
+	if aa || ba {
+		C := FMul(unwrapAdaptive(A), unwrapAdaptive(B))
+		if _, ok := C.(Z); ok {
+			return NewAdaptive(ar, bc)
+		}
+		return Adaptive(C)
+	}
+	if isLazy(A) || isLazy(B) {
+		return fMulLazy(A, B)
+	}
+	if v, ok := A.(V); ok {
+		return FMul(v.binary(), B)
+	}
+	if v, ok := B.(V); ok {
+		return FMul(A, v.binary())
+	}
+	_, az := A.(Z)
+	_, bz := B.(Z)
+	if !az && !bz && (isCompressed(A) || isCompressed(B)) {
+		return fMulCompressed(A, B)
+	}
+	_, ad := A.(*DM)
+	_, bd := B.(*DM)
+	if !az && !bz && (ad || bd) && !isSparse(A) && !isSparse(B) {
+		return fMulDense(Dense(A), Dense(B))
+	}
+	if x, ok := A.(*BM); ok {
+		if y, ok := B.(*BM); ok {
+			return fMulBand(x, y)
+		}
+	}
+	if x, ok := A.(Circ); ok {
+		if y, ok := B.(Circ); ok {
+			return x.Compose(y)
+		}
+	}
+	if x, ok := A.(*TM); ok {
+		if y, ok := B.(*TM); ok && x.upper == y.upper {
+			return fMulTri(x, y)
+		}
+	}
+	if x, ok := A.(Perm); ok {
+		if y, ok := B.(Perm); ok {
+			return x.Compose(y)
+		}
+	}
+	if !az && !bz && (isLarge(A) || isLarge(B) || ar > 65535 || ac > 65535 || bc > 65535) {
+		if C := fMulLargeStructured(A, B); C != nil {
+			return C
+		}
+		if isSparse(A) || isSparse(B) {
+			return fMulLSS(LSparse(A), LSparse(B))
+		}
+		return fMulLFull(LFull(A), LFull(B))
+	}
 	switch x := A.(type) {
 	case Z:
 		return Zeros(ar, bc)
+	case Comp:
+		if _, ok := B.(Z); !ok {
+			return fMulCompX(x, B)
+		}
+	case Perm:
+		if _, ok := B.(Z); !ok {
+			return fMulPermX(x, B, false)
+		}
+	case *BM:
+		if _, ok := B.(Z); !ok {
+			return fMulColumns(x, B, false)
+		}
+	case Toep:
+		if _, ok := B.(Z); !ok {
+			return fMulColumns(x, B, false)
+		}
+	case Circ:
+		if _, ok := B.(Z); !ok {
+			return fMulColumns(x, B, false)
+		}
 	case *SM:
 		return fMulSX(x, B)
 	case *PSM:
 		return fMulPSX(x, B)
 	case I:
+		// Only a square identity leaves the other argument unchanged.
 		switch B.(type) {
 		case *SM:
-			return Sparse(B)
+			if ar == ac {
+				return Sparse(B)
+			}
 		case *FM:
-			return Full(B)
+			if ar == ac {
+				return Full(B)
+			}
 		case I:
 			return Eye(ar, bc)
 		}
@@ -33,52 +138,151 @@ func FMul(A, B M) M {
 	switch x := B.(type) {
 	case Z:
 		return Zeros(ar, bc)
+	case Comp:
+		return fMulXComp(A, x)
+	case Perm:
+		return fMulXPerm(A, x, false)
+	case *BM:
+		return fMulXBand(A, x, false)
+	case Toep:
+		return fMulRows(A, x.Transpose(), false)
+	case Circ:
+		return fMulRows(A, x.Transpose(), false)
 	case *SM:
 		return fMulXS(A, x)
 	case *PSM:
 		return fMulXPS(A, x)
 	case I:
-		if _, ok := A.(*FM); ok {
+		if _, ok := A.(*FM); ok && br == bc {
 			return Full(A)
 		}
 	}
 	return fMulFull(A, B)
 }
 
-// fMulFull multiplies two matrices into a new FM.
+// fMulFull multiplies two matrices into a new FM. The product is computed on
+// dense row-major copies of the arguments.
 func fMulFull(A, B M) *FM {
-	ar, ac := A.Size()
+	return Full(fMulDense(Dense(A), Dense(B)))
+}
+
+// fMulLargeStructured multiplies two matrices, at least one of which is large,
+// when either is Perm, BM, TM, Toep, or Circ, using that structure rather than
+// enumerating its elements. The result is LSM if a Perm multiplies a sparse
+// matrix and LFM otherwise. Returns nil if neither argument has such a type.
+func fMulLargeStructured(A, B M) M {
+	if x, ok := A.(Perm); ok {
+		return fMulPermX(x, B, true)
+	}
+	if x, ok := B.(Perm); ok {
+		return fMulXPerm(A, x, true)
+	}
+	switch x := A.(type) {
+	case *BM:
+		return fMulColumns(x, B, true)
+	case *TM:
+		return fMulColumns(x, B, true)
+	case Toep:
+		return fMulColumns(x, B, true)
+	case Circ:
+		return fMulColumns(x, B, true)
+	}
+	switch x := B.(type) {
+	case *BM:
+		return fMulXBand(A, x, true)
+	case *TM:
+		// TM has no cheaper product from the right than the dense one.
+		return LFull(fMulDense(Dense(A), x.dense()))
+	case Toep:
+		return fMulRows(A, x.Transpose(), true)
+	case Circ:
+		return fMulRows(A, x.Transpose(), true)
+	}
+	return nil
+}
+
+// fullBits returns the elements of m in column-major order with the sentinel
+// bit, as stored in FM, or in LFM if large is true.
+func fullBits(m M, large bool) []big.Word {
+	if large {
+		return LFull(m).v.Bits()
+	}
+	return Full(m).v.Bits()
+}
+
+// fullOf creates a rows x cols FM, or LFM if large is true, holding the
+// column-major elements v, which must include the sentinel bit.
+func fullOf(rows, cols int, v []big.Word, large bool) M {
+	if large {
+		return &LFM{rows, cols, new(big.Int).SetBits(v)}
+	}
+	return &FM{uint16(rows), uint16(cols), new(big.Int).SetBits(v)}
+}
+
+// fMulColumns multiplies a matrix that multiplies vectors directly by another
+// matrix into a new FM, or LFM if large is true, one column of B at a time.
+func fMulColumns(A vecMuler, B M, large bool) M {
+	src := fullBits(B, large)
+	ar, br := A.Size()
 	_, bc := B.Size()
-	C := NewFull(ar, bc)
-	for c := 1; c <= bc; c++ {
-		for r := 1; r <= ar; r++ {
-			var d uint8
-			for i := 1; i <= ac; i++ {
-				d ^= check01(A.At(r, i)) & check01(B.At(i, c))
-			}
-			C.SetAt(r, c, to01(d != 0))
+	const w = bits.UintSize
+	v := make([]big.Word, ar*bc/w+1)
+	col := make([]big.Word, br/w+1)
+	x := new(big.Int)
+	for j := 0; j < bc; j++ {
+		for i := range col {
+			col[i] = 0
 		}
+		xorBitRange(col, src, j*br)
+		col[br/w] &= 1<<uint(br%w) - 1
+		orBitRange(v, A.mulVec(x.SetBits(col)).Bits(), j*ar)
 	}
-	return C
+	v[ar*bc/w] |= 1 << uint(ar*bc%w)
+	return fullOf(ar, bc, v, large)
+}
+
+// fMulRows multiplies a matrix by another given as its transpose Bt, which
+// multiplies vectors directly, into a new FM, or LFM if large is true. Each
+// row of the product is Bt times the corresponding row of A.
+func fMulRows(A M, Bt vecMuler, large bool) M {
+	D := Dense(A)
+	bc, _ := Bt.Size()
+	C := NewDense(D.r, bc)
+	for i := 0; i < D.r; i++ {
+		copy(C.row(i), words64(Bt.mulVec(bigWords64(D.row(i))), bc))
+	}
+	if large {
+		return LFull(C)
+	}
+	return Full(C)
 }
 
 // fMulSX multiplies a sparse matrix by another matrix into a new SM.
 func fMulSX(A *SM, B M) *SM {
-	ar, ac := A.Size()
+	ar, _ := A.Size()
 	_, bc := B.Size()
 	C := NewSparse(ar, bc)
 	switch X := B.(type) {
 	case *SM:
+		// Index the columns of the nonzero elements of B by row so that each
+		// element of A only meets the elements of B it multiplies.
+		rows := make(map[uint32][]uint32, len(X.v))
+		for k, b := range X.v {
+			if b != 0 {
+				rows[k&0xffff] = append(rows[k&0xffff], k&0xffff0000)
+			}
+		}
 		for j, a := range A.v {
 			if a == 0 {
 				continue
 			}
-			for k, b := range X.v {
-				if j>>16 == k&0xffff {
-					// The column of the A element equals the row of the B
-					// element. Their product is a term in the element of C at
-					// the row of A and the column of B.
-					C.v[k&0xffff0000|j&0x0000ffff] ^= a & b
+			// The column of the A element equals the row of each B element.
+			// Their product is a term in the element of C at the row of A and
+			// the column of B.
+			for _, c := range rows[j>>16] {
+				k := c | j&0x0000ffff
+				if C.v[k] ^= 1; C.v[k] == 0 {
+					delete(C.v, k)
 				}
 			}
 		}
@@ -138,7 +342,7 @@ func fMulSX(A *SM, B M) *SM {
 			r, c := j&0xffff, int(j>>16)
 			// This element multiplies with each element of the cth row of B
 			// into the rth row and respective column of C.
-			for i := 0; i < ac; i++ {
+			for i := 0; i < bc; i++ {
 				b := check01(B.At(c+1, i+1))
 				if b != 0 {
 					C.v[uint32(i)<<16|r] ^= 1
@@ -152,7 +356,7 @@ func fMulSX(A *SM, B M) *SM {
 // fMulPSX multiplies a sparse polynomial matrix by another matrix into a new
 // SM.
 func fMulPSX(A *PSM, B M) *SM {
-	ar, ac := A.Size()
+	ar, _ := A.Size()
 	_, bc := B.Size()
 	C := NewSparse(ar, bc)
 	switch X := B.(type) {
@@ -226,7 +430,7 @@ func fMulPSX(A *PSM, B M) *SM {
 			r, c := j&0xffff, int(j>>16)
 			// This element multiplies with each element of the cth row of B
 			// into the rth row and respective column of C.
-			for i := 0; i < ac; i++ {
+			for i := 0; i < bc; i++ {
 				b := check01(B.At(c+1, i+1))
 				if b != 0 {
 					C.v[uint32(i)<<16|r] ^= 1
@@ -239,7 +443,7 @@ func fMulPSX(A *PSM, B M) *SM {
 
 // fMulXS multiplies a matrix by an SM into a new SM.
 func fMulXS(A M, B *SM) *SM {
-	ar, ac := A.Size()
+	ar, _ := A.Size()
 	_, bc := B.Size()
 	C := NewSparse(ar, bc)
 	switch X := A.(type) {
@@ -247,7 +451,7 @@ func fMulXS(A M, B *SM) *SM {
 		for j, a := range B.v {
 			if a != 0 {
 				r, c := j&0xffff, j>>16
-				rr := (int(r) - X.n) % ar
+				rr := (int(r) + X.n) % ar
 				if rr < 0 {
 					rr += ar
 				}
@@ -265,7 +469,7 @@ func fMulXS(A M, B *SM) *SM {
 			}
 		} else {
 			for j, a := range B.v {
-				r, c := j&0xfff, j>>16
+				r, c := j&0xffff, j>>16
 				rr := int(r) + X.n
 				if a != 0 && rr >= 0 {
 					C.v[c<<16|uint32(rr)] = 1
@@ -278,9 +482,9 @@ func fMulXS(A M, B *SM) *SM {
 				continue
 			}
 			r, c := int(j&0xffff), j>>16
-			// This element multiplies with each element of the rth column of B
+			// This element multiplies with each element of the rth column of A
 			// into the respective row and cth column of C.
-			for i := 0; i < ac; i++ {
+			for i := 0; i < ar; i++ {
 				b := check01(A.At(i+1, r+1))
 				if b != 0 {
 					C.v[c<<16|uint32(i)] ^= 1
@@ -293,7 +497,7 @@ func fMulXS(A M, B *SM) *SM {
 
 // fMulXPS multiplies a matrix by a PSM into a new SM.
 func fMulXPS(A M, B *PSM) *SM {
-	ar, ac := A.Size()
+	ar, _ := A.Size()
 	_, bc := B.Size()
 	C := NewSparse(ar, bc)
 	switch X := A.(type) {
@@ -301,7 +505,7 @@ func fMulXPS(A M, B *PSM) *SM {
 		for j, a := range B.v {
 			if check01(a) != 0 {
 				r, c := j&0xffff, j>>16
-				rr := (int(r) - X.n) % ar
+				rr := (int(r) + X.n) % ar
 				if rr < 0 {
 					rr += ar
 				}
@@ -319,7 +523,7 @@ func fMulXPS(A M, B *PSM) *SM {
 			}
 		} else {
 			for j, a := range B.v {
-				r, c := j&0xfff, j>>16
+				r, c := j&0xffff, j>>16
 				rr := int(r) + X.n
 				if check01(a) != 0 && rr >= 0 {
 					C.v[c<<16|uint32(rr)] = 1
@@ -332,9 +536,9 @@ func fMulXPS(A M, B *PSM) *SM {
 				continue
 			}
 			r, c := int(j&0xffff), j>>16
-			// This element multiplies with each element of the rth column of B
+			// This element multiplies with each element of the rth column of A
 			// into the respective row and cth column of C.
-			for i := 0; i < ac; i++ {
+			for i := 0; i < ar; i++ {
 				b := check01(A.At(i+1, r+1))
 				if b != 0 {
 					C.v[c<<16|uint32(i)] ^= 1
@@ -344,3 +548,267 @@ func fMulXPS(A M, B *PSM) *SM {
 	}
 	return C
 }
+
+// fMulCompX multiplies a companion matrix by another matrix. Each column of
+// the product is the corresponding column of B, as a polynomial, times x
+// modulo the companion polynomial. The result is SM if B is sparse and FM
+// otherwise.
+func fMulCompX(A Comp, B M) M {
+	n := A.n
+	_, bc := B.Size()
+	if isSparse(B) {
+		C := NewSparse(n, bc)
+		toggle := func(k uint32) {
+			if C.v[k] ^= 1; C.v[k] == 0 {
+				delete(C.v, k)
+			}
+		}
+		for k, b := range Sparse(B).v {
+			if b == 0 {
+				continue
+			}
+			r, c := int(k&0xffff), k&0xffff0000
+			if r < n-1 {
+				toggle(c | uint32(r+1))
+				continue
+			}
+			for _, t := range A.tail {
+				toggle(c | uint32(t))
+			}
+		}
+		return C
+	}
+	src := Full(B).v.Bits()
+	const w = bits.UintSize
+	v := make([]big.Word, n*bc/w+1)
+	col := make([]big.Word, n/w+1)
+	for c := 0; c < bc; c++ {
+		for i := range col {
+			col[i] = 0
+		}
+		xorBitRange(col, src, c*n)
+		col[n/w] &= 1<<uint(n%w) - 1
+		// Shift up by one, then reduce if the term of x^n is set.
+		var carry big.Word
+		for i, x := range col {
+			col[i], carry = x<<1|carry, x>>(w-1)
+		}
+		if col[n/w]&(1<<uint(n%w)) != 0 {
+			for _, t := range A.tail {
+				col[t/w] ^= 1 << uint(t%w)
+			}
+		}
+		col[n/w] &= 1<<uint(n%w) - 1
+		orBitRange(v, col, c*n)
+	}
+	v[n*bc/w] |= 1 << uint(n*bc%w)
+	return &FM{uint16(n), uint16(bc), new(big.Int).SetBits(v)}
+}
+
+// fMulXComp multiplies a matrix by a companion matrix. Each column of the
+// product but the last is the next column of A, and the last is the sum of the
+// columns of A selected by the companion polynomial. The result is SM if A is
+// sparse and FM otherwise.
+func fMulXComp(A M, B Comp) M {
+	ar, _ := A.Size()
+	n := B.n
+	if isSparse(A) {
+		C := NewSparse(ar, n)
+		last := make(map[int]bool, len(B.tail))
+		for _, t := range B.tail {
+			last[t] = true
+		}
+		toggle := func(k uint32) {
+			if C.v[k] ^= 1; C.v[k] == 0 {
+				delete(C.v, k)
+			}
+		}
+		for k, a := range Sparse(A).v {
+			if a == 0 {
+				continue
+			}
+			r, c := k&0xffff, int(k>>16)
+			if c > 0 {
+				toggle(uint32(c-1)<<16 | r)
+			}
+			if last[c] {
+				toggle(uint32(n-1)<<16 | r)
+			}
+		}
+		return C
+	}
+	src := Full(A).v.Bits()
+	const w = bits.UintSize
+	v := make([]big.Word, ar*n/w+1)
+	// Drop the first column, then fill the last.
+	xorBitRange(v, src, ar)
+	k := ar * (n - 1)
+	v[k/w] &= 1<<uint(k%w) - 1
+	for i := k/w + 1; i < len(v); i++ {
+		v[i] = 0
+	}
+	col := make([]big.Word, ar/w+1)
+	for _, t := range B.tail {
+		xorBitRange(col, src, t*ar)
+	}
+	col[ar/w] &= 1<<uint(ar%w) - 1
+	orBitRange(v, col, ar*(n-1))
+	v[ar*n/w] |= 1 << uint(ar*n%w)
+	return &FM{uint16(ar), uint16(n), new(big.Int).SetBits(v)}
+}
+
+// PMul multiplies two matrices over GF(2)[x], so that unlike FMul, elements
+// may be polynomials of any degree. If both arguments are sparse (SM, PSM, I,
+// R, or S), the result is PSM; otherwise, it is PFM. If either argument is a
+// large type or any dimension exceeds 65535, the result is LPSM or LPFM
+// instead. If either argument is Z, the result is Z. Panics if the inner
+// dimensions of the matrices are not equal.
+func PMul(A, B M) M {
+	ar, ac := A.Size()
+	br, bc := B.Size()
+	if ac != br {
+		panic(fmt.Sprintf("inner dimension mismatch: %dx%d * %dx%d", ar, ac, br, bc))
+	}
+	_, az := A.(Z)
+	_, bz := B.(Z)
+	if az || bz {
+		return Zeros(ar, bc)
+	}
+	as, bs := isSparse(A), isSparse(B)
+	if isLarge(A) || isLarge(B) || ar > 65535 || ac > 65535 || bc > 65535 {
+		switch {
+		case as && bs:
+			return pMulLSS(LPSparse(A), LPSparse(B))
+		case bs:
+			return pMulLXS(A, LPSparse(B))
+		}
+		return pMulLSX(LPSparse(A), B)
+	}
+	switch {
+	case as && bs:
+		return pMulSS(PSparse(A), PSparse(B))
+	case as:
+		return pMulSX(PSparse(A), B)
+	case bs:
+		return pMulXS(A, PSparse(B))
+	}
+	return pMulFull(PFull(A), PFull(B))
+}
+
+// isCompressed returns whether m is CSR or CSC.
+func isCompressed(m M) bool {
+	switch m.(type) {
+	case *CSR, *CSC:
+		return true
+	}
+	return false
+}
+
+// isSparse returns whether m is one of the types with sparse storage. This
+// includes LSM and LPSM, which Sparse and PSparse cannot hold, so callers must
+// route large matrices elsewhere first.
+func isSparse(m M) bool {
+	switch m.(type) {
+	case *SM, *PSM, *LSM, *LPSM, *CSR, *CSC, I, R, S, Comp, Perm:
+		return true
+	}
+	return false
+}
+
+// pMulSS multiplies two sparse polynomial matrices into a new PSM.
+func pMulSS(A, B *PSM) *PSM {
+	ar, _ := A.Size()
+	_, bc := B.Size()
+	C := NewPSparse(ar, bc)
+	// Index the nonzero elements of B by row.
+	rows := make(map[uint32][]uint32, len(B.v))
+	for k, b := range B.v {
+		if b.Sign() != 0 {
+			rows[k&0xffff] = append(rows[k&0xffff], k)
+		}
+	}
+	var t big.Int
+	for j, a := range A.v {
+		if a.Sign() == 0 {
+			continue
+		}
+		for _, k := range rows[j>>16] {
+			i := k&0xffff0000 | j&0x0000ffff
+			q, ok := C.v[i]
+			if !ok {
+				q = new(big.Int)
+				C.v[i] = q
+			}
+			q.Xor(q, PolyMul(&t, a, B.v[k]))
+		}
+	}
+	for k, q := range C.v {
+		if q.Sign() == 0 {
+			delete(C.v, k)
+		}
+	}
+	return C
+}
+
+// pMulSX multiplies a sparse polynomial matrix by any matrix into a new PFM.
+func pMulSX(A *PSM, B M) *PFM {
+	ar, _ := A.Size()
+	_, bc := B.Size()
+	C := NewPFull(ar, bc)
+	var t big.Int
+	for j, a := range A.v {
+		if a.Sign() == 0 {
+			continue
+		}
+		r, c := int(j&0xffff), int(j>>16)
+		// This element multiplies with each element of the cth row of B into
+		// the rth row and respective column of C.
+		for i := 0; i < bc; i++ {
+			q := C.v[i*ar+r]
+			q.Xor(q, PolyMul(&t, a, B.At(c+1, i+1)))
+		}
+	}
+	return C
+}
+
+// pMulXS multiplies any matrix by a sparse polynomial matrix into a new PFM.
+func pMulXS(A M, B *PSM) *PFM {
+	ar, _ := A.Size()
+	_, bc := B.Size()
+	C := NewPFull(ar, bc)
+	var t big.Int
+	for j, b := range B.v {
+		if b.Sign() == 0 {
+			continue
+		}
+		r, c := int(j&0xffff), int(j>>16)
+		// This element multiplies with each element of the rth column of A
+		// into the respective row and cth column of C.
+		for i := 0; i < ar; i++ {
+			q := C.v[c*ar+i]
+			q.Xor(q, PolyMul(&t, A.At(i+1, r+1), b))
+		}
+	}
+	return C
+}
+
+// pMulFull multiplies two full polynomial matrices into a new PFM.
+func pMulFull(A, B *PFM) *PFM {
+	ar, ac := A.Size()
+	_, bc := B.Size()
+	C := NewPFull(ar, bc)
+	var t big.Int
+	for c := 0; c < bc; c++ {
+		for i := 0; i < ac; i++ {
+			b := B.v[c*ac+i]
+			if b.Sign() == 0 {
+				continue
+			}
+			for r := 0; r < ar; r++ {
+				q := C.v[c*ar+r]
+				q.Xor(q, PolyMul(&t, A.v[i*ar+r], b))
+			}
+		}
+	}
+	return C
+}
@@ -0,0 +1,115 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// largeN is a dimension too large for SM and FM.
+const largeN = 70000
+
+// largeSparse creates a rows x cols LSM with a few ones in each row or column,
+// whichever is shorter.
+func largeSparse(r *rand.Rand, rows, cols int) *LSM {
+	A := NewLSparse(rows, cols)
+	one := big.NewInt(1)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if rows < cols {
+				A.SetAt(j+1, r.Intn(cols)+1, one)
+			} else {
+				A.SetAt(r.Intn(rows)+1, j+1, one)
+			}
+		}
+	}
+	return A
+}
+
+// randBits returns a random vector of n bits.
+func randBits(r *rand.Rand, n int) *big.Int {
+	x := new(big.Int)
+	for i := 0; i < n; i++ {
+		if r.Intn(2) != 0 {
+			x.SetBit(x, i, 1)
+		}
+	}
+	return x
+}
+
+// checkLargeProduct checks C = A B at random elements by summing the elements
+// of A and B along the nonzero elements of the sparse argument S, which must
+// be A or B.
+func checkLargeProduct(t *testing.T, r *rand.Rand, A, B, C M, S *LSM) {
+	t.Helper()
+	ar, ac := A.Size()
+	_, bc := B.Size()
+	if cr, cc := C.Size(); cr != ar || cc != bc {
+		t.Fatalf("product of %dx%d and %dx%d has size %dx%d", ar, ac, ac, bc, cr, cc)
+	}
+	for n := 0; n < 200; n++ {
+		i, j := r.Intn(ar)+1, r.Intn(bc)+1
+		var want uint
+		for k, b := range S.v {
+			if b == 0 {
+				continue
+			}
+			sr, sc := int(k&0xffffffff)+1, int(k>>32)+1
+			if S == B && sc == j {
+				want ^= A.At(i, sr).Bit(0)
+			}
+			if S == A && sr == i {
+				want ^= B.At(sc, j).Bit(0)
+			}
+		}
+		if got := C.At(i, j).Bit(0); got != want {
+			t.Fatalf("element (%d,%d) is %d, want %d", i, j, got, want)
+		}
+	}
+}
+
+func TestFMulLargeStructured(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	col, row := randBits(r, largeN), randBits(r, largeN)
+	row.SetBit(row, 0, col.Bit(0))
+	p := r.Perm(largeN)
+	for i := range p {
+		p[i]++
+	}
+	band := NewBanded(largeN, largeN, 2, 1)
+	for i := 1; i <= largeN; i++ {
+		band.SetAt(i, i, big.NewInt(int64(r.Intn(2))))
+		if i > 2 {
+			band.SetAt(i, i-2, big.NewInt(int64(r.Intn(2))))
+		}
+	}
+	cases := []struct {
+		name string
+		m    M
+		// sparse is whether a product with LSM is LSM.
+		sparse bool
+	}{
+		{"Toep", Toeplitz(col, row, largeN, largeN), false},
+		{"Circ", Circulant(col, largeN), false},
+		{"Perm", Permutation(p), true},
+		{"BM", band, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name+"*LSM", func(t *testing.T) {
+			B := largeSparse(r, largeN, 3)
+			C := FMul(c.m, B)
+			if _, ok := C.(*LSM); ok != c.sparse {
+				t.Fatalf("product has type %T", C)
+			}
+			checkLargeProduct(t, r, c.m, B, C, B)
+		})
+		t.Run("LSM*"+c.name, func(t *testing.T) {
+			A := largeSparse(r, 3, largeN)
+			C := FMul(A, c.m)
+			if _, ok := C.(*LSM); ok != c.sparse {
+				t.Fatalf("product has type %T", C)
+			}
+			checkLargeProduct(t, r, A, c.m, C, A)
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// PolyOrder computes the order of f, the least period of the powers of x
+// modulo f. pre is the pre-period, the least a such that x^(a+e) = x^a mod f
+// for some e > 0, and period is the least such e. If f is coprime to x, then
+// pre is 0 and period is the least e such that f divides x^e - 1. For an LFSR
+// with characteristic polynomial f, these are the length of the transient
+// before the state sequence becomes periodic and the length of its cycle.
+// Panics if f is zero.
+//
+// PolyOrder factors f and 2^d - 1 for the degree d of each irreducible
+// factor. If some 2^d - 1 has two or more large prime factors that Pollard's
+// rho method cannot separate, PolyOrder panics, unless the factors have been
+// supplied with SetMersenneFactors.
+func PolyOrder(f *big.Int) (pre, period *big.Int) {
+	if f.Sign() == 0 {
+		panic("cannot take order of zero polynomial")
+	}
+	pre, period = new(big.Int), big.NewInt(1)
+	var g big.Int
+	for _, p := range PolyFactor(f) {
+		if p.P.Cmp(polyX) == 0 {
+			// Each factor of x delays the cycle by one step.
+			pre.SetInt64(int64(p.E))
+			continue
+		}
+		e := irreducibleOrder(p.P)
+		// The order of p^k is the order of p times the least power of two
+		// not less than k.
+		t := uint(0)
+		for 1<<t < p.E {
+			t++
+		}
+		e.Lsh(e, t)
+		g.GCD(nil, nil, period, e)
+		period.Mul(period, e.Quo(e, &g))
+	}
+	return pre, period
+}
+
+// PolyPrimitive reports whether f is primitive, i.e. irreducible with x
+// generating the multiplicative group of GF(2)[x]/(f). An LFSR has maximal
+// period 2^n - 1 exactly when its characteristic polynomial of degree n is
+// primitive. PolyPrimitive may panic for the same reasons as PolyOrder.
+func PolyPrimitive(f *big.Int) bool {
+	if !PolyIrreducible(f) || f.Cmp(polyX) == 0 {
+		return false
+	}
+	d := PolyDeg(f)
+	m := NewModulus(f)
+	e := new(big.Int).Sub(new(big.Int).Lsh(oneP, uint(d)), oneP)
+	var t, x big.Int
+	for _, q := range mersenneFactors(d) {
+		if m.Exp(&x, polyX, t.Quo(e, q)).Cmp(oneP) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// irreducibleOrder returns the order of x modulo an irreducible polynomial p
+// other than x.
+func irreducibleOrder(p *big.Int) *big.Int {
+	m := NewModulus(p)
+	return elemOrder(m, polyX, mersenneFactors(m.Deg()))
+}
+
+// elemOrder returns the multiplicative order of the non-zero g modulo the
+// irreducible modulus m. The order divides 2^d - 1, where d is the degree of
+// the modulus, and qs must be the distinct prime factors of 2^d - 1.
+func elemOrder(m *Modulus, g *big.Int, qs []*big.Int) *big.Int {
+	d := m.Deg()
+	e := new(big.Int).Sub(new(big.Int).Lsh(oneP, uint(d)), oneP)
+	var t, r, x big.Int
+	for _, q := range qs {
+		for {
+			t.QuoRem(e, q, &r)
+			if r.Sign() != 0 || m.Exp(&x, g, &t).Cmp(oneP) != 0 {
+				break
+			}
+			e.Set(&t)
+		}
+	}
+	return e
+}
+
+var mersenneCache = struct {
+	sync.Mutex
+	m map[int][]*big.Int
+}{m: make(map[int][]*big.Int)}
+
+// mersenneFactors returns the distinct prime factors of 2^d - 1 in increasing
+// order. The returned slice must not be modified. Panics if 2^d - 1 cannot be
+// factored.
+func mersenneFactors(d int) []*big.Int {
+	r, err := tryMersenneFactors(d)
+	if err != nil {
+		panic(err.Error())
+	}
+	return r
+}
+
+// tryMersenneFactors returns the distinct prime factors of 2^d - 1 in
+// increasing order, or a *FactorTooLargeError if it has a composite factor
+// that Pollard's rho method cannot split. The returned slice must not be
+// modified. Results are cached, so each 2^d - 1 is only factored once.
+func tryMersenneFactors(d int) ([]*big.Int, error) {
+	mersenneCache.Lock()
+	r, ok := mersenneCache.m[d]
+	mersenneCache.Unlock()
+	if ok {
+		return r, nil
+	}
+	// 2^k - 1 divides 2^d - 1 for each k dividing d, so factoring the smaller
+	// numbers first leaves only the primitive part of 2^d - 1 to factor.
+	n := new(big.Int).Sub(new(big.Int).Lsh(oneP, uint(d)), oneP)
+	seen := make(map[string]bool)
+	var q, m big.Int
+	for k := 2; k < d; k++ {
+		if d%k != 0 {
+			continue
+		}
+		ps, err := tryMersenneFactors(k)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range ps {
+			if !seen[p.String()] {
+				seen[p.String()] = true
+				r = append(r, p)
+			}
+			for {
+				q.QuoRem(n, p, &m)
+				if m.Sign() != 0 {
+					break
+				}
+				n.Set(&q)
+			}
+		}
+	}
+	ps, err := factorInt(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ps {
+		if !seen[p.String()] {
+			seen[p.String()] = true
+			r = append(r, p)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Cmp(r[j]) < 0 })
+	mersenneCache.Lock()
+	mersenneCache.m[d] = r
+	mersenneCache.Unlock()
+	return r, nil
+}
+
+// SetMersenneFactors supplies the distinct prime factors of 2^d - 1, e.g. from
+// published tables, for PolyOrder, PolyPrimitive, DiscreteLog, and Distance
+// to use instead of factoring it themselves. This lets them handle degrees d
+// for which 2^d - 1 has large factors that Pollard's rho method cannot
+// separate. Factors may be given in any order. Panics if d is not positive or
+// if the factors are not exactly the distinct primes dividing 2^d - 1.
+func SetMersenneFactors(d int, factors []*big.Int) {
+	if d <= 0 {
+		panic(fmt.Sprintf("cannot set factors of 2^%d - 1: exponent must be positive", d))
+	}
+	n := new(big.Int).Sub(new(big.Int).Lsh(oneP, uint(d)), oneP)
+	r := make([]*big.Int, 0, len(factors))
+	var q, m big.Int
+	for _, p := range factors {
+		if p.Cmp(oneP) <= 0 || !p.ProbablyPrime(20) {
+			panic(fmt.Sprintf("cannot set factors of 2^%d - 1: %v is not prime", d, p))
+		}
+		if q.QuoRem(n, p, &m); m.Sign() != 0 {
+			panic(fmt.Sprintf("cannot set factors of 2^%d - 1: %v is repeated or does not divide it", d, p))
+		}
+		for m.Sign() == 0 {
+			n.Set(&q)
+			q.QuoRem(n, p, &m)
+		}
+		r = append(r, new(big.Int).Set(p))
+	}
+	if n.Cmp(oneP) != 0 {
+		panic(fmt.Sprintf("cannot set factors of 2^%d - 1: cofactor %v remains", d, n))
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Cmp(r[j]) < 0 })
+	mersenneCache.Lock()
+	mersenneCache.m[d] = r
+	mersenneCache.Unlock()
+}
+
+// factorInt returns the distinct prime factors of n, which must be positive, or
+// a *FactorTooLargeError if n has a composite factor that Pollard's rho method
+// cannot split.
+func factorInt(n *big.Int) ([]*big.Int, error) {
+	var r []*big.Int
+	n = new(big.Int).Set(n)
+	var q, m, p big.Int
+	for k := int64(2); k < 1000; k++ {
+		p.SetInt64(k)
+		if q.QuoRem(n, &p, &m); m.Sign() != 0 {
+			continue
+		}
+		r = append(r, big.NewInt(k))
+		for m.Sign() == 0 {
+			n.Set(&q)
+			q.QuoRem(n, &p, &m)
+		}
+	}
+	ps, err := factorRho(n)
+	if err != nil {
+		return nil, err
+	}
+	return append(r, ps...), nil
+}
+
+// factorRho returns the prime factors of n, which must have no prime factors
+// less than 1000, possibly with repeats, or a *FactorTooLargeError if brent
+// cannot split a composite factor.
+func factorRho(n *big.Int) ([]*big.Int, error) {
+	if n.Cmp(oneP) == 0 {
+		return nil, nil
+	}
+	if n.ProbablyPrime(1) {
+		return []*big.Int{n}, nil
+	}
+	d := brent(n)
+	if d == nil {
+		return nil, &FactorTooLargeError{Factor: new(big.Int).Set(n), Composite: true}
+	}
+	q := new(big.Int).Quo(n, d)
+	r, err := factorRho(d)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := factorRho(q)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ps {
+		dup := false
+		for _, s := range r {
+			if s.Cmp(p) == 0 {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			r = append(r, p)
+		}
+	}
+	return r, nil
+}
+
+// maxRhoSteps is the number of iterations of Pollard's rho method that brent
+// attempts before giving up on a number. Rho finds a prime factor p in about
+// sqrt(p) steps, so this reliably splits numbers whose second largest prime
+// factor is below about 2^40, and fails on harder ones within a second or
+// so. Factors beyond that can be supplied with SetMersenneFactors.
+const maxRhoSteps = 1 << 20
+
+// brent finds a non-trivial factor of the composite n using Brent's variant
+// of Pollard's rho method. Returns nil if no factor is found within
+// maxRhoSteps iterations.
+func brent(n *big.Int) *big.Int {
+	var y, x, ys, q, g, t big.Int
+	c := big.NewInt(1)
+	const batch = 128
+	steps := 0
+	for {
+		y.SetInt64(2)
+		q.SetInt64(1)
+		g.SetInt64(1)
+		for r := 1; g.Cmp(oneP) == 0; r *= 2 {
+			x.Set(&y)
+			for i := 0; i < r; i++ {
+				rhoStep(&y, c, n)
+			}
+			for k := 0; k < r && g.Cmp(oneP) == 0; k += batch {
+				ys.Set(&y)
+				for i := 0; i < batch && i < r-k; i++ {
+					rhoStep(&y, c, n)
+					q.Mul(&q, t.Sub(&x, &y).Abs(&t)).Mod(&q, n)
+				}
+				g.GCD(nil, nil, &q, n)
+				steps += batch
+				if steps > maxRhoSteps {
+					return nil
+				}
+			}
+		}
+		if g.Cmp(n) == 0 {
+			// The batch overshot; step back through it one at a time.
+			for {
+				rhoStep(&ys, c, n)
+				g.GCD(nil, nil, t.Sub(&x, &ys).Abs(&t), n)
+				if g.Cmp(oneP) != 0 {
+					break
+				}
+			}
+		}
+		if g.Cmp(n) != 0 {
+			return new(big.Int).Set(&g)
+		}
+		c.Add(c, oneP)
+	}
+}
+
+// rhoStep sets y to y^2 + c mod n.
+func rhoStep(y, c, n *big.Int) {
+	y.Mul(y, y).Add(y, c).Mod(y, n)
+}
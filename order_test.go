@@ -0,0 +1,128 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// orderNaive computes the pre-period and period of the powers of x modulo f
+// by stepping through them.
+func orderNaive(f *big.Int) (pre, period int) {
+	seen := make(map[string]int)
+	x := PolyMod(new(big.Int), big.NewInt(1), f)
+	for k := 0; ; k++ {
+		if i, ok := seen[x.String()]; ok {
+			return i, k - i
+		}
+		seen[x.String()] = k
+		PolyMod(x, x.Lsh(x, 1), f)
+	}
+}
+
+func TestPolyOrder(t *testing.T) {
+	cases := []struct {
+		f           int64
+		pre, period int64
+	}{
+		{0x3, 0, 1},     // x + 1
+		{0x7, 0, 3},     // x^2 + x + 1
+		{0x13, 0, 15},   // x^4 + x + 1
+		{0x1f, 0, 5},    // x^4 + x^3 + x^2 + x + 1
+		{0x4c, 2, 15},   // x^2 (x^4 + x + 1)
+		{0x15, 0, 6},    // (x^2 + x + 1)^2
+		{0x2, 1, 1},     // x
+		{0x11b, 0, 51},  // x^8 + x^4 + x^3 + x + 1
+		{0x11d, 0, 255}, // x^8 + x^4 + x^3 + x^2 + 1
+	}
+	for _, c := range cases {
+		pre, period := PolyOrder(big.NewInt(c.f))
+		if pre.Int64() != c.pre || period.Int64() != c.period {
+			t.Errorf("PolyOrder(%b) = %v, %v, want %d, %d", c.f, pre, period, c.pre, c.period)
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		f := randPoly(r, r.Intn(12)+1)
+		pre, period := PolyOrder(f)
+		wp, wq := orderNaive(f)
+		if pre.Int64() != int64(wp) || period.Int64() != int64(wq) {
+			t.Errorf("PolyOrder(%b) = %v, %v, want %d, %d", f, pre, period, wp, wq)
+		}
+	}
+}
+
+func TestPolyPrimitive(t *testing.T) {
+	// The number of primitive polynomials of each degree from 1 to 10.
+	counts := []int{1, 1, 2, 2, 6, 6, 18, 16, 48, 60}
+	var f big.Int
+	for n, want := range counts {
+		n++
+		got := 0
+		for k := int64(1) << uint(n); k < 1<<uint(n+1); k++ {
+			f.SetInt64(k)
+			prim := PolyPrimitive(&f)
+			pre, period := PolyOrder(&f)
+			if prim != (PolyIrreducible(&f) && pre.Sign() == 0 && period.Int64() == 1<<uint(n)-1) {
+				t.Fatalf("PolyPrimitive(%b) = %t", k, prim)
+			}
+			if prim {
+				got++
+			}
+		}
+		if got != want {
+			t.Errorf("%d primitive polynomials of degree %d, want %d", got, n, want)
+		}
+	}
+}
+
+func TestMersenneFactors(t *testing.T) {
+	one := big.NewInt(1)
+	for d := 1; d <= 100; d++ {
+		n := new(big.Int).Sub(new(big.Int).Lsh(one, uint(d)), one)
+		var q, m big.Int
+		for i, p := range mersenneFactors(d) {
+			if !p.ProbablyPrime(20) {
+				t.Errorf("factor %v of 2^%d - 1 is not prime", p, d)
+			}
+			if i > 0 && mersenneFactors(d)[i-1].Cmp(p) >= 0 {
+				t.Errorf("factors of 2^%d - 1 are out of order", d)
+			}
+			if q.QuoRem(n, p, &m); m.Sign() != 0 {
+				t.Fatalf("%v does not divide 2^%d - 1", p, d)
+			}
+			for m.Sign() == 0 {
+				n.Set(&q)
+				q.QuoRem(n, p, &m)
+			}
+		}
+		if n.Cmp(one) != 0 {
+			t.Errorf("2^%d - 1 has unlisted cofactor %v", d, n)
+		}
+	}
+}
+
+func TestSetMersenneFactors(t *testing.T) {
+	// 2^11 - 1 = 23 * 89.
+	SetMersenneFactors(11, []*big.Int{big.NewInt(89), big.NewInt(23)})
+	if r := mersenneFactors(11); len(r) != 2 || r[0].Int64() != 23 || r[1].Int64() != 89 {
+		t.Errorf("factors of 2^11 - 1 are %v, want [23 89]", r)
+	}
+	bad := [][]*big.Int{
+		{big.NewInt(23)},
+		{big.NewInt(23), big.NewInt(23), big.NewInt(89)},
+		{big.NewInt(2047)},
+		{big.NewInt(1), big.NewInt(23), big.NewInt(89)},
+		{big.NewInt(7), big.NewInt(23), big.NewInt(89)},
+	}
+	for _, fs := range bad {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("SetMersenneFactors(11, %v) did not panic", fs)
+				}
+			}()
+			SetMersenneFactors(11, fs)
+		}()
+	}
+}
@@ -0,0 +1,191 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Perm is an immutable permutation matrix. For a permutation p of 1, ..., n,
+// the matrix has a one in row p(c) of each column c, so multiplying it by a
+// column vector moves the element at index c to index p(c), and multiplying a
+// matrix on the left by it moves row c to row p(c).
+type Perm struct {
+	immutableM
+	// p holds the zero-based image of each zero-based index.
+	p []int
+}
+
+// Permutation creates the permutation matrix of the permutation taking each
+// one-based index i to p[i-1]. The slice is copied. Panics if p is empty or
+// is not a permutation of 1, ..., len(p).
+func Permutation(p []int) Perm {
+	if len(p) == 0 {
+		panic("can't create 0x0 Perm: size must be positive")
+	}
+	seen := make([]bool, len(p))
+	q := make([]int, len(p))
+	for i, j := range p {
+		if j <= 0 || j > len(p) || seen[j-1] {
+			panic(fmt.Sprintf("can't create Perm: %v is not a permutation", p))
+		}
+		seen[j-1] = true
+		q[i] = j - 1
+	}
+	return Perm{p: q}
+}
+
+// Size returns the size of the matrix.
+func (m Perm) Size() (rows, cols int) {
+	return len(m.p), len(m.p)
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be modified.
+func (m Perm) At(r, c int) *big.Int {
+	if r <= 0 || r > len(m.p) || c <= 0 || c > len(m.p) {
+		panic(fmt.Sprintf("index (%d,%d) out of bounds (size %dx%d)", r, c, len(m.p), len(m.p)))
+	}
+	return to01(m.p[c-1] == r-1)
+}
+
+// Image returns the one-based image of the one-based index i under the
+// permutation.
+func (m Perm) Image(i int) int {
+	return m.p[i-1] + 1
+}
+
+// Compose returns the product m*q, which is the permutation applying q and
+// then m. Panics if the permutations have different sizes.
+func (m Perm) Compose(q Perm) Perm {
+	if len(m.p) != len(q.p) {
+		panic(fmt.Sprintf("inner dimension mismatch: %dx%d * %dx%d", len(m.p), len(m.p), len(q.p), len(q.p)))
+	}
+	r := make([]int, len(m.p))
+	for i, j := range q.p {
+		r[i] = m.p[j]
+	}
+	return Perm{p: r}
+}
+
+// Inverse returns the inverse permutation, which is also the transpose.
+func (m Perm) Inverse() Perm {
+	r := make([]int, len(m.p))
+	for i, j := range m.p {
+		r[j] = i
+	}
+	return Perm{p: r}
+}
+
+// Cycles returns the decomposition of the permutation into disjoint cycles of
+// length at least 2, as one-based indices. Each cycle starts with its least
+// index and continues with successive images, and the cycles are ordered by
+// their least indices.
+func (m Perm) Cycles() [][]int {
+	var r [][]int
+	seen := make([]bool, len(m.p))
+	for i := range m.p {
+		if seen[i] || m.p[i] == i {
+			continue
+		}
+		var c []int
+		for j := i; !seen[j]; j = m.p[j] {
+			seen[j] = true
+			c = append(c, j+1)
+		}
+		r = append(r, c)
+	}
+	return r
+}
+
+// Sign returns 1 if the permutation is even and -1 if it is odd. Over GF(2),
+// the determinant of any permutation matrix is 1 regardless.
+func (m Perm) Sign() int {
+	s := 1
+	for _, c := range m.Cycles() {
+		if len(c)%2 == 0 {
+			s = -s
+		}
+	}
+	return s
+}
+
+// forEach calls f with the zero-based row and column of each nonzero element.
+func (m Perm) forEach(f func(r, c int)) {
+	for c, r := range m.p {
+		f(r, c)
+	}
+}
+
+// fMulPermX multiplies a permutation matrix by another matrix, moving each row
+// of B to its image. The result is SM if B is sparse and FM otherwise, or LSM
+// and LFM respectively if large is true.
+func fMulPermX(A Perm, B M, large bool) M {
+	n := len(A.p)
+	_, bc := B.Size()
+	if isSparse(B) && large {
+		C := NewLSparse(n, bc)
+		for k, b := range LSparse(B).v {
+			if b != 0 {
+				C.v[k&^0xffffffff|uint64(A.p[k&0xffffffff])] = 1
+			}
+		}
+		return C
+	}
+	if isSparse(B) {
+		C := NewSparse(n, bc)
+		for k, b := range Sparse(B).v {
+			if b != 0 {
+				C.v[k&0xffff0000|uint32(A.p[k&0xffff])] = 1
+			}
+		}
+		return C
+	}
+	C := new(big.Int).SetBit(new(big.Int), n*bc, 1)
+	forMatrixBits(new(big.Int).SetBits(fullBits(B, large)), n, bc, func(r, c int) {
+		C.SetBit(C, c*n+A.p[r], 1)
+	})
+	return fullOf(n, bc, C.Bits(), large)
+}
+
+// fMulXPerm multiplies a matrix by a permutation matrix, so that column c of
+// the product is column p(c) of A. The result is SM if A is sparse and FM
+// otherwise, or LSM and LFM respectively if large is true.
+func fMulXPerm(A M, B Perm, large bool) M {
+	ar, _ := A.Size()
+	n := len(B.p)
+	if isSparse(A) && large {
+		inv := B.Inverse()
+		C := NewLSparse(ar, n)
+		for k, a := range LSparse(A).v {
+			if a != 0 {
+				C.v[lkey(int(k&0xffffffff), inv.p[k>>32])] = 1
+			}
+		}
+		return C
+	}
+	if isSparse(A) {
+		inv := B.Inverse()
+		C := NewSparse(ar, n)
+		for k, a := range Sparse(A).v {
+			if a != 0 {
+				C.v[uint32(inv.p[k>>16])<<16|k&0xffff] = 1
+			}
+		}
+		return C
+	}
+	src := fullBits(A, large)
+	const w = bits.UintSize
+	v := make([]big.Word, ar*n/w+1)
+	col := make([]big.Word, ar/w+1)
+	for c, j := range B.p {
+		for i := range col {
+			col[i] = 0
+		}
+		xorBitRange(col, src, j*ar)
+		col[ar/w] &= 1<<uint(ar%w) - 1
+		orBitRange(v, col, c*ar)
+	}
+	v[ar*n/w] |= 1 << uint(ar*n%w)
+	return fullOf(ar, n, v, large)
+}
@@ -32,15 +32,20 @@ func NewPSparse(rows, cols int) *PSM {
 }
 
 // PSparse converts any type of matrix to a sparse polynomial matrix. Panics if
-// the argument is too large. Types PSM, SM, FM, I, Z, R, and S are
-// special-cased. All other types are filled in O(mn) time.
+// the argument is too large. Types PSM, SM, FM, LPSM, LSM, LFM, DM, CSR, CSC,
+// BM, TM, Toep, Circ, I, Z, R, S, Comp, and Perm are special-cased, Sum and
+// Product are evaluated first, and V is read from its viewed matrix. All other
+// types are filled in O(mn) time.
 func PSparse(m M) *PSM {
+	m = unwrapAdaptive(m)
 	rows, cols := m.Size()
 	if rows > 65535 || cols > 65535 {
 		panic(fmt.Sprintf("cannot make %dx%d matrix: maximum dimension is 65535", rows, cols))
 	}
 	B := PSM{uint16(rows), uint16(cols), make(map[uint32]*big.Int)}
 	switch A := m.(type) {
+	case V:
+		return PSparse(A.poly())
 	case *PSM:
 		for k, v := range A.v {
 			if v.Sign() != 0 {
@@ -69,15 +74,25 @@ func PSparse(m M) *PSM {
 			}
 			k += bits.UintSize
 		}
+	case *LPSM:
+		for k, v := range A.v {
+			if v.Sign() != 0 {
+				B.v[uint32(k>>32)<<16|uint32(k&0xffff)] = new(big.Int).Set(v)
+			}
+		}
+	case *LSM, *LFM, *DM, *CSR, *CSC, Comp, Perm, *BM, *TM, Toep, Circ, Sum, Product:
+		for k := range Sparse(A).v {
+			B.v[k] = big.NewInt(1)
+		}
 	case I:
-		for r := 0; r < rows; r++ {
+		for r := 0; r < rows && r < cols; r++ {
 			B.v[uint32(r)*0x00010001] = big.NewInt(1)
 		}
 	case Z:
 		// do nothing
 	case R:
 		for i := 0; i < rows; i++ {
-			r := i + A.n%rows
+			r := (i + A.n) % rows
 			if r < 0 {
 				r += rows
 			}
@@ -95,8 +110,8 @@ func PSparse(m M) *PSM {
 		}
 	default:
 		for c := 0; c < cols; c++ {
-			for r := 0; r < cols; r++ {
-				q := A.At(r, c)
+			for r := 0; r < rows; r++ {
+				q := A.At(r+1, c+1)
 				if q.Sign() != 0 {
 					B.v[uint32(c)<<16|uint32(r)] = new(big.Int).Set(q)
 				}
@@ -115,13 +130,12 @@ func (A *PSM) Size() (rows, cols int) {
 // is a reference if and only if it is nonzero.
 func (A *PSM) At(r, c int) *big.Int {
 	k := A.index(r, c)
-	if p := A.v[k]; p == nil || p.Sign() == 0 {
-		if p.Sign() == 0 {
-			delete(A.v, k)
-		}
-		return p
+	p := A.v[k]
+	if p == nil || p.Sign() == 0 {
+		delete(A.v, k)
+		return new(big.Int)
 	}
-	return new(big.Int)
+	return p
 }
 
 // SetAt sets the polynomial at the given one-based index. The polynomial is
@@ -158,7 +172,7 @@ func (A *PSM) MulAt(r, c int, p *big.Int) *big.Int {
 		A.v[k] = q
 		return q
 	}
-	return q.Mul(q, p)
+	return PolyMul(q, q, p)
 }
 
 // index panics if the given row or column indices are out of bounds and
@@ -200,9 +214,13 @@ func NewPFull(rows, cols int) *PFM {
 }
 
 // PFull converts any type of matrix to a full polynomial matrix. Panics if the
-// argument is too large. There are no special cases; converting any matrix
-// results in m*n calls to m.At().
+// argument is too large. The only special case is V, which is first copied
+// from its viewed matrix; converting any other matrix results in m*n calls to
+// m.At().
 func PFull(m M) *PFM {
+	if v, ok := m.(V); ok {
+		m = v.poly()
+	}
 	rows, cols := m.Size()
 	if rows > 65535 || cols > 65535 {
 		panic(fmt.Sprintf("cannot make %dx%d matrix: maximum dimension is 65535", rows, cols))
@@ -210,7 +228,7 @@ func PFull(m M) *PFM {
 	B := PFM{uint16(rows), uint16(cols), make([]*big.Int, rows*cols)}
 	for c := 0; c < cols; c++ {
 		for r := 0; r < rows; r++ {
-			B.v[c*rows+r] = new(big.Int).Set(m.At(r, c))
+			B.v[c*rows+r] = new(big.Int).Set(m.At(r+1, c+1))
 		}
 	}
 	return &B
@@ -243,7 +261,7 @@ func (A *PFM) AddAt(r, c int, p *big.Int) *big.Int {
 // index by another. The returned value is a reference.
 func (A *PFM) MulAt(r, c int, p *big.Int) *big.Int {
 	k := A.index(r, c)
-	return A.v[k].Mul(A.v[k], p)
+	return PolyMul(A.v[k], A.v[k], p)
 }
 
 // index panics if the given row or column indices are out of bounds and
@@ -0,0 +1,284 @@
+package gof2
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// Polynomials over GF(2) are represented as big ints in which bit i is the
+// coefficient of x^i. Signs are ignored; only the magnitude of an argument is
+// used. Results are always non-negative.
+
+// PolyDeg returns the degree of p. The degree of the zero polynomial is -1.
+func PolyDeg(p *big.Int) int {
+	return p.BitLen() - 1
+}
+
+// weight returns the number of terms in p.
+func weight(p *big.Int) int {
+	w := 0
+	for _, v := range p.Bits() {
+		w += bits.OnesCount(uint(v))
+	}
+	return w
+}
+
+// PolyMul sets z to the product of x and y and returns z. Unlike big.Int.Mul,
+// there are no carries between coefficients.
+func PolyMul(z, x, y *big.Int) *big.Int {
+	return z.SetBits(mulWords(x.Bits(), y.Bits()))
+}
+
+// PolySqr sets z to the square of x and returns z. Squaring in GF(2)[x] only
+// spreads out the coefficients, so it takes linear time.
+func PolySqr(z, x *big.Int) *big.Int {
+	return z.SetBits(sqrWords(x.Bits()))
+}
+
+// PolyDivMod sets q and r to the quotient and remainder of x divided by y and
+// returns the pair. Either of q and r may be nil, in which case a new value is
+// allocated. Panics if y is zero.
+func PolyDivMod(q, r, x, y *big.Int) (*big.Int, *big.Int) {
+	if q == nil {
+		q = new(big.Int)
+	}
+	if r == nil {
+		r = new(big.Int)
+	}
+	qw, rw := divWords(x.Bits(), y.Bits(), true)
+	q.SetBits(qw)
+	r.SetBits(rw)
+	return q, r
+}
+
+// PolyMod sets z to the remainder of x divided by y and returns z. Panics if y
+// is zero.
+func PolyMod(z, x, y *big.Int) *big.Int {
+	_, rw := divWords(x.Bits(), y.Bits(), false)
+	return z.SetBits(rw)
+}
+
+// mulWords returns the carry-less product of two little-endian word vectors.
+// The result does not alias either argument.
+func mulWords(x, y []big.Word) []big.Word {
+	x, y = normWords(x), normWords(y)
+	if len(x) == 0 || len(y) == 0 {
+		return nil
+	}
+	if len(x) < len(y) {
+		x, y = y, x
+	}
+	n := len(y)
+	if n < karatsubaCutoff {
+		z := make([]big.Word, len(x)+n)
+		mulBasic(z, x, y)
+		return normWords(z)
+	}
+	// Cut x into pieces the same length as y so that every product is
+	// balanced.
+	z := make([]big.Word, (len(x)+n-1)/n*n+n)
+	piece := make([]big.Word, n)
+	for i := 0; i < len(x); i += n {
+		p := x[i:]
+		if len(p) > n {
+			p = p[:n]
+		} else if len(p) < n {
+			copy(piece, p)
+			for j := len(p); j < n; j++ {
+				piece[j] = 0
+			}
+			p = piece
+		}
+		karatsuba(z[i:], p, y)
+	}
+	return normWords(z)
+}
+
+// karatsubaCutoff is the operand length in words below which multiplication
+// uses the schoolbook method.
+const karatsubaCutoff = 24
+
+// karatsuba adds the product of x and y, which must have equal lengths, into
+// z, which must have length at least 2*len(x).
+func karatsuba(z, x, y []big.Word) {
+	n := len(x)
+	if n < karatsubaCutoff {
+		mulBasic(z, x, y)
+		return
+	}
+	h := n / 2
+	x0, x1 := x[:h], x[h:]
+	y0, y1 := y[:h], y[h:]
+	// xs and ys are (x0+x1) and (y0+y1), with x0 and y0 padded to the length
+	// of the high halves.
+	m := n - h
+	xs := make([]big.Word, m)
+	ys := make([]big.Word, m)
+	copy(xs, x1)
+	copy(ys, y1)
+	xorWords(xs, x0)
+	xorWords(ys, y0)
+	p0 := make([]big.Word, 2*h)
+	p2 := make([]big.Word, 2*m)
+	p1 := make([]big.Word, 2*m)
+	karatsuba(p0, x0, y0)
+	karatsuba(p2, x1, y1)
+	karatsuba(p1, xs, ys)
+	xorWords(p1, p0)
+	xorWords(p1, p2)
+	xorWords(z, p0)
+	xorWords(z[h:], p1)
+	xorWords(z[2*h:], p2)
+}
+
+// mulBasic adds the product of x and y into z using the schoolbook method. z
+// must have length at least len(x)+len(y).
+func mulBasic(z, x, y []big.Word) {
+	for i, a := range x {
+		if a == 0 {
+			continue
+		}
+		for j, b := range y {
+			hi, lo := clmul(uint(a), uint(b))
+			z[i+j] ^= big.Word(lo)
+			z[i+j+1] ^= big.Word(hi)
+		}
+	}
+}
+
+// clmul returns the carry-less product of two words.
+func clmul(a, b uint) (hi, lo uint) {
+	const w = bits.UintSize
+	// Build a table of the products of the low w-3 bits of a with every
+	// polynomial of degree less than four. Every entry fits in one word.
+	var tab [16]uint
+	a0 := a << 3 >> 3
+	tab[1] = a0
+	for i := 2; i < 16; i += 2 {
+		tab[i] = tab[i/2] << 1
+		tab[i+1] = tab[i] ^ a0
+	}
+	for i := w - 4; i >= 0; i -= 4 {
+		hi = hi<<4 | lo>>(w-4)
+		lo = lo<<4 ^ tab[b>>uint(i)&15]
+	}
+	// Add in the products with the top three bits of a.
+	for i := uint(w - 3); i < w; i++ {
+		if a>>i&1 != 0 {
+			lo ^= b << i
+			hi ^= b >> (w - i)
+		}
+	}
+	return hi, lo
+}
+
+// sqrWords returns the square of x.
+func sqrWords(x []big.Word) []big.Word {
+	x = normWords(x)
+	z := make([]big.Word, 2*len(x))
+	for i, v := range x {
+		lo, hi := spread(uint(v))
+		z[2*i] = big.Word(lo)
+		z[2*i+1] = big.Word(hi)
+	}
+	return normWords(z)
+}
+
+// spread interleaves zeros between the bits of v, returning the low and high
+// halves of the result.
+func spread(v uint) (lo, hi uint) {
+	const h = bits.UintSize / 2
+	for i := uint(0); i < h; i += 8 {
+		lo |= uint(spreadTab[v>>i&0xff]) << (2 * i)
+		hi |= uint(spreadTab[v>>(i+h)&0xff]) << (2 * i)
+	}
+	return lo, hi
+}
+
+// spreadTab maps each byte to the same bits spread into the even positions
+// of a 16-bit value.
+var spreadTab = func() (t [256]uint16) {
+	for i := range t {
+		for j := uint(0); j < 8; j++ {
+			t[i] |= uint16(i>>j&1) << (2 * j)
+		}
+	}
+	return t
+}()
+
+// divWords returns the quotient and remainder of x divided by y. The quotient
+// is only computed if wantQ is true. Panics if y is zero.
+func divWords(x, y []big.Word, wantQ bool) (q, r []big.Word) {
+	y = normWords(y)
+	if len(y) == 0 {
+		panic("division by zero polynomial")
+	}
+	r = normWords(append([]big.Word(nil), x...))
+	dy := wordsDeg(y)
+	dr := wordsDeg(r)
+	if dr < dy {
+		return nil, r
+	}
+	if wantQ {
+		q = make([]big.Word, (dr-dy)/bits.UintSize+1)
+	}
+	for i := dr; i >= dy; i-- {
+		if r[i/bits.UintSize]>>uint(i%bits.UintSize)&1 == 0 {
+			continue
+		}
+		s := i - dy
+		xorShifted(r, y, s)
+		if wantQ {
+			q[s/bits.UintSize] |= 1 << uint(s%bits.UintSize)
+		}
+	}
+	return normWords(q), normWords(r)
+}
+
+// xorShifted adds y*x^s into z. Bits that would land past the end of z are
+// discarded.
+func xorShifted(z, y []big.Word, s int) {
+	w, b := s/bits.UintSize, uint(s%bits.UintSize)
+	if b == 0 {
+		for j, v := range y {
+			if w+j >= len(z) {
+				return
+			}
+			z[w+j] ^= v
+		}
+		return
+	}
+	for j, v := range y {
+		if w+j >= len(z) {
+			return
+		}
+		z[w+j] ^= v << b
+		if w+j+1 < len(z) {
+			z[w+j+1] ^= v >> (bits.UintSize - b)
+		}
+	}
+}
+
+// xorWords adds y into z, which must be at least as long.
+func xorWords(z, y []big.Word) {
+	for i, v := range y {
+		z[i] ^= v
+	}
+}
+
+// normWords trims high zero words from x.
+func normWords(x []big.Word) []big.Word {
+	n := len(x)
+	for n > 0 && x[n-1] == 0 {
+		n--
+	}
+	return x[:n]
+}
+
+// wordsDeg returns the degree of the polynomial in the normalized vector x.
+func wordsDeg(x []big.Word) int {
+	if len(x) == 0 {
+		return -1
+	}
+	return len(x)*bits.UintSize - bits.LeadingZeros(uint(x[len(x)-1])) - 1
+}
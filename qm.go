@@ -0,0 +1,294 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// QFM is a full matrix of size up to 65535x65535 over the quotient ring
+// GF(2)[x]/(f) for a fixed polynomial f. Every element is kept reduced modulo
+// f, so elements never grow past the degree of f. When f is irreducible, this
+// is a matrix over the field GF(2^n), n the degree of f.
+type QFM struct {
+	m    *Modulus
+	r, c uint16
+	v    []*big.Int
+}
+
+// NewQFull creates a zero matrix of the given size over GF(2)[x]/(f), where f
+// is the polynomial of m. Panics if either size is non-positive or greater
+// than 65535.
+func NewQFull(m *Modulus, rows, cols int) *QFM {
+	if rows <= 0 || cols <= 0 {
+		panic(fmt.Sprintf("cannot make %dx%d matrix: size must be positive", rows, cols))
+	}
+	if rows > 65535 || cols > 65535 {
+		panic(fmt.Sprintf("cannot make %dx%d matrix: maximum dimension is 65535", rows, cols))
+	}
+	v := make([]*big.Int, rows*cols)
+	for i := range v {
+		v[i] = new(big.Int)
+	}
+	return &QFM{m: m, r: uint16(rows), c: uint16(cols), v: v}
+}
+
+// QFull converts any type of matrix to a full matrix over GF(2)[x]/(f), where
+// f is the polynomial of m, reducing each element. Panics if the argument is
+// too large. Converting any matrix results in m*n calls to A.At().
+func QFull(m *Modulus, A M) *QFM {
+	rows, cols := A.Size()
+	if rows > 65535 || cols > 65535 {
+		panic(fmt.Sprintf("cannot make %dx%d matrix: maximum dimension is 65535", rows, cols))
+	}
+	B := QFM{m, uint16(rows), uint16(cols), make([]*big.Int, rows*cols)}
+	for c := 0; c < cols; c++ {
+		for r := 0; r < rows; r++ {
+			B.v[c*rows+r] = m.Reduce(new(big.Int), A.At(r+1, c+1))
+		}
+	}
+	return &B
+}
+
+// Modulus returns the modulus of the matrix's ring.
+func (A *QFM) Modulus() *Modulus {
+	return A.m
+}
+
+// Size returns the size of the matrix.
+func (A *QFM) Size() (rows, cols int) {
+	return int(A.r), int(A.c)
+}
+
+// At returns the element at the given one-based index. The returned element is
+// always a reference, and it must remain reduced.
+func (A *QFM) At(r, c int) *big.Int {
+	return A.v[A.index(r, c)]
+}
+
+// SetAt sets an element to p reduced modulo f. Unlike PFM, p itself is not
+// retained.
+func (A *QFM) SetAt(r, c int, p *big.Int) {
+	A.v[A.index(r, c)] = A.m.Reduce(new(big.Int), p)
+}
+
+// AddAt adds a polynomial to that in the given index. The returned value is a
+// reference.
+func (A *QFM) AddAt(r, c int, p *big.Int) *big.Int {
+	k := A.index(r, c)
+	A.v[k].Xor(A.v[k], p)
+	return A.m.Reduce(A.v[k], A.v[k])
+}
+
+// MulAt multiplies the polynomial in a given index by another modulo f. The
+// returned value is a reference.
+func (A *QFM) MulAt(r, c int, p *big.Int) *big.Int {
+	k := A.index(r, c)
+	return A.m.Mul(A.v[k], A.v[k], p)
+}
+
+// index panics if the given row or column indices are out of bounds and
+// returns the corresponding vector coordinate otherwise. The vector is
+// column-major.
+func (A *QFM) index(r, c int) int {
+	if r--; r < 0 || r >= int(A.r) {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r+1, A.r, A.c))
+	}
+	if c--; c < 0 || c >= int(A.c) {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c+1, A.r, A.c))
+	}
+	return c*int(A.r) + r
+}
+
+// Mul returns the product A*B over the ring of A. Elements of B are reduced
+// as they are used. Panics if the inner dimensions of the matrices are not
+// equal.
+func (A *QFM) Mul(B M) *QFM {
+	ar, ac := A.Size()
+	br, bc := B.Size()
+	if ac != br {
+		panic(fmt.Sprintf("inner dimension mismatch: %dx%d * %dx%d", ar, ac, br, bc))
+	}
+	b := QFull(A.m, B)
+	C := NewQFull(A.m, ar, bc)
+	var t big.Int
+	for j := 0; j < bc; j++ {
+		for i := 0; i < ar; i++ {
+			// Accumulate the unreduced products and reduce once.
+			s := C.v[j*ar+i]
+			for k := 0; k < ac; k++ {
+				s.Xor(s, PolyMul(&t, A.v[k*ar+i], b.v[j*br+k]))
+			}
+			A.m.Reduce(s, s)
+		}
+	}
+	return C
+}
+
+// Echelon computes a row echelon form E of A along with an invertible matrix
+// U such that U*A = E. Each pivot is a divisor of f, 1 if the pivot column has
+// any unit, and each element above a pivot has lower degree than the pivot;
+// in particular, over a field, E is the reduced row echelon form of A.
+//
+// Elimination runs Euclid's algorithm down each column on the reduced
+// representatives of the elements, which need no further reduction, so it
+// works over any quotient ring, not only fields.
+func (A *QFM) Echelon() (E, U *QFM) {
+	rows, _ := A.Size()
+	a, u := A.qrows(), eyeRows(rows)
+	A.m.echelon(a, u)
+	return qfmRows(A.m, a), qfmRows(A.m, u)
+}
+
+// Det computes the determinant of a square matrix over its ring. Panics if A
+// is not square.
+func (A *QFM) Det() *big.Int {
+	rows, cols := A.Size()
+	if rows != cols {
+		panic(fmt.Sprintf("cannot take determinant of %dx%d matrix: matrix must be square", rows, cols))
+	}
+	a := A.qrows()
+	// The row operations of echelon each have determinant 1, and pivot
+	// scaling is only by units, which the determinant must undo.
+	s := A.m.echelon(a, nil)
+	d := big.NewInt(1)
+	for i := 0; i < rows; i++ {
+		A.m.Mul(d, d, a[i][i])
+	}
+	return A.m.Mul(d, d, s)
+}
+
+// Inverse computes the inverse of a square matrix over its ring. If A is not
+// invertible, i.e. if its determinant is not a unit, the result is nil.
+// Panics if A is not square.
+func (A *QFM) Inverse() *QFM {
+	rows, cols := A.Size()
+	if rows != cols {
+		panic(fmt.Sprintf("cannot invert %dx%d matrix: matrix must be square", rows, cols))
+	}
+	a, u := A.qrows(), eyeRows(rows)
+	A.m.echelon(a, u)
+	for i := 0; i < rows; i++ {
+		if a[i][i].Cmp(oneP) != 0 {
+			return nil
+		}
+	}
+	return qfmRows(A.m, u)
+}
+
+// echelon transforms a into row echelon form as described by QFM.Echelon,
+// applying the same row operations to u if it is not nil. The result is the
+// product of the units by which pivot rows were scaled.
+func (m *Modulus) echelon(a, u [][]*big.Int) *big.Int {
+	rows, cols := len(a), len(a[0])
+	var q, t, g big.Int
+	scale := big.NewInt(1)
+	// addRow adds q times row j into row i of a and u, keeping them reduced.
+	addRow := func(i, j int, q *big.Int) {
+		for _, b := range [][][]*big.Int{a, u} {
+			if b == nil {
+				continue
+			}
+			for c, p := range b[j] {
+				if p.Sign() != 0 {
+					b[i][c].Xor(b[i][c], m.Mul(&t, q, p))
+				}
+			}
+		}
+	}
+	r := 0
+	for c := 0; c < cols && r < rows; c++ {
+		for {
+			p, _ := minDegreeCol(a, r, c)
+			if p < 0 {
+				break
+			}
+			a[r], a[p] = a[p], a[r]
+			if u != nil {
+				u[r], u[p] = u[p], u[r]
+			}
+			done := true
+			for i := r + 1; i < rows; i++ {
+				if a[i][c].Sign() == 0 {
+					continue
+				}
+				// Degrees are less than that of f, so the remainder needs no
+				// reduction.
+				PolyDivMod(&q, nil, a[i][c], a[r][c])
+				addRow(i, r, &q)
+				if a[i][c].Sign() != 0 {
+					done = false
+				}
+			}
+			if done {
+				break
+			}
+		}
+		if a[r][c].Sign() == 0 {
+			continue
+		}
+		// Replace the pivot by its gcd with f, a divisor of f, by scaling by
+		// a unit. The pivot p and g = gcd(p, f) generate the same ideal, so
+		// p = g*w for some unit w.
+		PolyGCD(&g, a[r][c], m.f)
+		if g.Cmp(a[r][c]) != 0 {
+			w := m.unitRatio(a[r][c], &g)
+			m.Mul(scale, scale, w)
+			m.Inverse(w, w)
+			for _, b := range [][][]*big.Int{a, u} {
+				if b == nil {
+					continue
+				}
+				for _, p := range b[r] {
+					m.Mul(p, p, w)
+				}
+			}
+		}
+		for i := 0; i < r; i++ {
+			if PolyDeg(a[i][c]) >= PolyDeg(a[r][c]) {
+				PolyDivMod(&q, nil, a[i][c], a[r][c])
+				addRow(i, r, &q)
+			}
+		}
+		r++
+	}
+	return scale
+}
+
+// unitRatio returns a unit w such that p = g*w, where g = gcd(p, f).
+func (m *Modulus) unitRatio(p, g *big.Int) *big.Int {
+	// p/g is coprime to f/g but not necessarily to f. Adding multiples of
+	// f/g changes nothing modulo f after multiplying by g, so search
+	// p/g + k*(f/g) for a unit, which exists by the Chinese remainder
+	// theorem.
+	var pg, fg, w, t big.Int
+	PolyDivMod(&pg, nil, p, g)
+	PolyDivMod(&fg, nil, m.f, g)
+	for k := big.NewInt(0); ; k.Add(k, oneP) {
+		PolyMul(&t, k, &fg)
+		w.Xor(&pg, &t)
+		m.Reduce(&w, &w)
+		if r := m.Inverse(new(big.Int), &w); r != nil {
+			return new(big.Int).Set(&w)
+		}
+	}
+}
+
+// qrows copies the elements of A into a slice of rows.
+func (A *QFM) qrows() [][]*big.Int {
+	rows, cols := A.Size()
+	a := make([][]*big.Int, rows)
+	for i := range a {
+		a[i] = make([]*big.Int, cols)
+		for j := range a[i] {
+			a[i][j] = new(big.Int).Set(A.v[j*rows+i])
+		}
+	}
+	return a
+}
+
+// qfmRows creates a QFM from a slice of rows of reduced polynomials. The
+// polynomials are not copied.
+func qfmRows(m *Modulus, a [][]*big.Int) *QFM {
+	P := pfmRows(a)
+	return &QFM{m, P.r, P.c, P.v}
+}
@@ -0,0 +1,116 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// qmModuli returns moduli for fields and for rings with zero divisors.
+func qmModuli() []*big.Int {
+	return []*big.Int{
+		big.NewInt(0x3),   // x + 1, so GF(2)
+		big.NewInt(0x11b), // x^8 + x^4 + x^3 + x + 1, irreducible
+		big.NewInt(0x11),  // x^4 + 1 = (x + 1)^4
+		big.NewInt(0x7e),  // x (x + 1)^2 (x^3 + x + 1)
+	}
+}
+
+// reduced returns the elements of A reduced modulo f as a PFM.
+func reduced(m *Modulus, A M) *PFM {
+	rows, cols := A.Size()
+	B := NewPFull(rows, cols)
+	for i := 1; i <= rows; i++ {
+		for j := 1; j <= cols; j++ {
+			B.SetAt(i, j, m.Reduce(new(big.Int), A.At(i, j)))
+		}
+	}
+	return B
+}
+
+func TestQFMMul(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, f := range qmModuli() {
+		m := NewModulus(f)
+		for _, sz := range [][3]int{{1, 1, 1}, {3, 4, 2}, {6, 6, 6}} {
+			A := QFull(m, randPFM(r, sz[0], sz[1], 12))
+			B := randPFM(r, sz[1], sz[2], 12)
+			if !sameElements(A.Mul(B), reduced(m, PMul(A, B))) {
+				t.Errorf("product of %dx%d and %dx%d matrices mod %v is wrong", sz[0], sz[1], sz[1], sz[2], f)
+			}
+		}
+	}
+}
+
+func TestQFMDetInverse(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	one := big.NewInt(1)
+	for _, f := range qmModuli() {
+		m := NewModulus(f)
+		for n := 1; n <= 6; n++ {
+			for i := 0; i < 10; i++ {
+				P := randPFM(r, n, n, PolyDeg(f))
+				A := QFull(m, P)
+				// The determinant commutes with reduction.
+				want := m.Reduce(new(big.Int), Det(P))
+				if got := A.Det(); got.Cmp(want) != 0 {
+					t.Errorf("det of %dx%d matrix mod %v is %v, want %v", n, n, f, got, want)
+				}
+				B := A.Inverse()
+				unit := PolyGCD(new(big.Int), want, f).Cmp(one) == 0
+				if B == nil {
+					if unit {
+						t.Errorf("%dx%d matrix with unit determinant %v mod %v has no inverse", n, n, want, f)
+					}
+					continue
+				}
+				if !unit {
+					t.Errorf("%dx%d matrix with determinant %v mod %v has an inverse", n, n, want, f)
+				}
+				if !sameElements(B.Mul(A), reduced(m, Eye(n, n))) || !sameElements(A.Mul(B), reduced(m, Eye(n, n))) {
+					t.Errorf("inverse of %dx%d matrix mod %v is wrong", n, n, f)
+				}
+			}
+		}
+	}
+}
+
+func TestQFMEchelon(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, f := range qmModuli() {
+		m := NewModulus(f)
+		for _, sz := range [][2]int{{1, 1}, {3, 5}, {5, 3}, {4, 4}} {
+			A := QFull(m, randPFM(r, sz[0], sz[1], PolyDeg(f)))
+			E, U := A.Echelon()
+			if !sameElements(U.Mul(A), E) {
+				t.Errorf("U*A is not E for %dx%d matrix mod %v", sz[0], sz[1], f)
+			}
+			if d := U.Det(); PolyGCD(new(big.Int), d, f).Cmp(big.NewInt(1)) != 0 {
+				t.Errorf("det U for %dx%d matrix mod %v is %v, not a unit", sz[0], sz[1], f, d)
+			}
+			last := 0
+			for i := 1; i <= sz[0]; i++ {
+				c := 1
+				for c <= sz[1] && E.At(i, c).Sign() == 0 {
+					c++
+				}
+				if c > sz[1] {
+					last = sz[1] + 1
+					continue
+				}
+				if c <= last {
+					t.Fatalf("pivot of row %d of E mod %v is in column %d, after %d", i, f, c, last)
+				}
+				last = c
+				if PolyMod(new(big.Int), f, E.At(i, c)).Sign() != 0 {
+					t.Errorf("pivot %v of E does not divide %v", E.At(i, c), f)
+				}
+				for k := 1; k < i; k++ {
+					if PolyDeg(E.At(k, c)) >= PolyDeg(E.At(i, c)) {
+						t.Errorf("element (%d,%d) of E mod %v is not reduced by its pivot", k, c, f)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"sort"
+)
+
+// FieldFactor is an irreducible factor over a Field of some polynomial, along
+// with its multiplicity.
+type FieldFactor struct {
+	// P is the monic irreducible factor as its list of coefficients in the
+	// field, in increasing order of degree.
+	P []*big.Int
+	// E is the multiplicity of P.
+	E int
+}
+
+// Roots finds all distinct roots in F of the GF(2)[x] polynomial f, in
+// increasing order. This is the Berlekamp trace algorithm: the GCD of f and
+// z^(2^n) - z isolates the product of the linear factors of f over F, and
+// GCDs with Tr(b*z) for basis elements b split it into individual roots.
+// Panics if f is zero.
+func (F *Field) Roots(f *big.Int) []*big.Int {
+	return F.RootsOf(F.lift(f))
+}
+
+// RootsOf finds all distinct roots in F of the polynomial with coefficients c
+// in F, listed in increasing order of degree. For example, the roots of a
+// quadratic z^2 + b*z + c are RootsOf([]*big.Int{c, b, 1}). Panics if the
+// polynomial is zero.
+func (F *Field) RootsOf(c []*big.Int) []*big.Int {
+	a := F.pmonic(F.pnorm(c))
+	h := F.pfrob(fpoly{new(big.Int), big.NewInt(1)}, a, F.n)
+	g := F.pgcd(a, F.padd(h, fpoly{new(big.Int), big.NewInt(1)}))
+	r := F.splitRoots(g, 0)
+	sort.Slice(r, func(i, j int) bool { return r[i].Cmp(r[j]) < 0 })
+	return r
+}
+
+// Factor factors the GF(2)[x] polynomial f into monic irreducible polynomials
+// over F. The factors are sorted by degree, then by coefficients. Panics if
+// f is zero.
+func (F *Field) Factor(f *big.Int) []FieldFactor {
+	return F.FactorOf(F.lift(f))
+}
+
+// FactorOf factors the polynomial with coefficients c in F, listed in
+// increasing order of degree, into monic irreducible polynomials over F. The
+// factors are sorted by degree, then by coefficients. The leading coefficient
+// of the polynomial is discarded. Panics if the polynomial is zero.
+func (F *Field) FactorOf(c []*big.Int) []FieldFactor {
+	a := F.pmonic(F.pnorm(c))
+	var r []FieldFactor
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range F.psquareFree(a) {
+		for _, d := range F.pdistinctDegree(s.P) {
+			for _, p := range F.pequalDegree(d.P, d.E, rng) {
+				r = append(r, FieldFactor{p, s.E})
+			}
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		a, b := r[i].P, r[j].P
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		for k := range a {
+			if c := a[k].Cmp(b[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return r
+}
+
+// lift converts a GF(2)[x] polynomial to a polynomial over F.
+func (F *Field) lift(f *big.Int) fpoly {
+	c := make(fpoly, f.BitLen())
+	for i := range c {
+		c[i] = big.NewInt(int64(f.Bit(i)))
+	}
+	return c
+}
+
+// splitRoots returns the roots of g, a monic product of distinct linear
+// factors, using the traces of b*z for basis elements b = x^i, x^(i+1), ....
+func (F *Field) splitRoots(g fpoly, i int) []*big.Int {
+	switch g.deg() {
+	case -1, 0:
+		return nil
+	case 1:
+		// z + c has root c.
+		return []*big.Int{new(big.Int).Set(g[0])}
+	}
+	if i >= F.n {
+		// The trace form is non-degenerate, so some basis element must
+		// separate any two distinct roots.
+		panic("gof2: trace splitting failed")
+	}
+	var b big.Int
+	F.Reduce(&b, new(big.Int).Lsh(oneP, uint(i)))
+	t := F.pmod(fpoly{new(big.Int), &b}, g)
+	T := F.ptrace(t, g, F.n)
+	u := F.pgcd(g, T)
+	if k := u.deg(); k > 0 && k < g.deg() {
+		v, _ := F.pdivmod(g, u)
+		return append(F.splitRoots(u, i+1), F.splitRoots(v, i+1)...)
+	}
+	return F.splitRoots(g, i+1)
+}
+
+// psquareFree returns the square-free factorization of the monic a.
+func (F *Field) psquareFree(a fpoly) []FieldFactor {
+	if a.deg() < 1 {
+		return nil
+	}
+	var r []FieldFactor
+	d := F.pderiv(a)
+	if d.deg() < 0 {
+		for _, s := range F.psquareFree(F.psqrt(a)) {
+			r = append(r, FieldFactor{s.P, 2 * s.E})
+		}
+		return r
+	}
+	c := F.pgcd(a, d)
+	w, _ := F.pdivmod(a, c)
+	for i := 1; w.deg() > 0; i++ {
+		y := F.pgcd(w, c)
+		z, _ := F.pdivmod(w, y)
+		if z.deg() > 0 {
+			r = append(r, FieldFactor{z, i})
+		}
+		w = y
+		c, _ = F.pdivmod(c, y)
+	}
+	if c.deg() > 0 {
+		for _, s := range F.psquareFree(F.psqrt(c)) {
+			r = append(r, FieldFactor{s.P, 2 * s.E})
+		}
+	}
+	return r
+}
+
+// pdistinctDegree splits a square-free monic polynomial into products of
+// irreducible factors of equal degree, stored in the E field of each result.
+func (F *Field) pdistinctDegree(a fpoly) []FieldFactor {
+	var r []FieldFactor
+	z := fpoly{new(big.Int), big.NewInt(1)}
+	h := F.pmod(z, a)
+	for d := 1; 2*d <= a.deg(); d++ {
+		// Each irreducible factor of degree d divides z^(q^d) - z, where
+		// q = 2^n is the size of the field.
+		h = F.pfrob(h, a, F.n)
+		u := F.pgcd(a, F.padd(h, z))
+		if u.deg() > 0 {
+			r = append(r, FieldFactor{u, d})
+			a, _ = F.pdivmod(a, u)
+			h = F.pmod(h, a)
+		}
+	}
+	if a.deg() > 0 {
+		r = append(r, FieldFactor{a, a.deg()})
+	}
+	return r
+}
+
+// pequalDegree splits a, a monic product of distinct irreducible polynomials
+// each of degree d, into its factors.
+func (F *Field) pequalDegree(a fpoly, d int, rng *rand.Rand) [][]*big.Int {
+	if a.deg() <= d {
+		return [][]*big.Int{a}
+	}
+	lim := new(big.Int).Lsh(oneP, uint(F.n))
+	for {
+		// The trace from GF(q^d) down to GF(2) of a random residue is zero
+		// modulo about half of the factors.
+		t := make(fpoly, a.deg())
+		for i := range t {
+			t[i] = new(big.Int).Rand(rng, lim)
+		}
+		T := F.ptrace(F.pnorm(t), a, F.n*d)
+		u := F.pgcd(a, T)
+		if k := u.deg(); k > 0 && k < a.deg() {
+			v, _ := F.pdivmod(a, u)
+			return append(F.pequalDegree(u, d, rng), F.pequalDegree(v, d, rng)...)
+		}
+	}
+}
+
+// fpoly is a polynomial over a Field with coefficients in increasing order of
+// degree. A normalized fpoly has a non-zero leading coefficient; the zero
+// polynomial is empty.
+type fpoly []*big.Int
+
+// deg returns the degree of p, which must be normalized.
+func (p fpoly) deg() int {
+	return len(p) - 1
+}
+
+// pnorm returns a normalized copy of c with reduced coefficients.
+func (F *Field) pnorm(c []*big.Int) fpoly {
+	p := make(fpoly, len(c))
+	for i, v := range c {
+		p[i] = F.Reduce(new(big.Int), v)
+	}
+	return p.trim()
+}
+
+// trim removes zero leading coefficients from p.
+func (p fpoly) trim() fpoly {
+	n := len(p)
+	for n > 0 && p[n-1].Sign() == 0 {
+		n--
+	}
+	return p[:n]
+}
+
+// padd returns a+b.
+func (F *Field) padd(a, b fpoly) fpoly {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	r := make(fpoly, len(a))
+	for i := range a {
+		r[i] = new(big.Int).Set(a[i])
+		if i < len(b) {
+			r[i].Xor(r[i], b[i])
+		}
+	}
+	return r.trim()
+}
+
+// pmul returns a*b.
+func (F *Field) pmul(a, b fpoly) fpoly {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	r := make(fpoly, len(a)+len(b)-1)
+	for i := range r {
+		r[i] = new(big.Int)
+	}
+	var t big.Int
+	for i, x := range a {
+		if x.Sign() == 0 {
+			continue
+		}
+		for j, y := range b {
+			r[i+j].Xor(r[i+j], F.Mul(&t, x, y))
+		}
+	}
+	return r.trim()
+}
+
+// pdivmod returns the quotient and remainder of a divided by b. Panics if b
+// is zero.
+func (F *Field) pdivmod(a, b fpoly) (q, r fpoly) {
+	if len(b) == 0 {
+		panic("division by zero polynomial")
+	}
+	r = make(fpoly, len(a))
+	for i := range a {
+		r[i] = new(big.Int).Set(a[i])
+	}
+	db := b.deg()
+	if a.deg() < db {
+		return nil, r
+	}
+	q = make(fpoly, a.deg()-db+1)
+	inv := F.Inv(new(big.Int), b[db])
+	var t big.Int
+	for i := a.deg(); i >= db; i-- {
+		c := F.Mul(new(big.Int), r[i], inv)
+		q[i-db] = c
+		if c.Sign() == 0 {
+			continue
+		}
+		for j, v := range b {
+			r[i-db+j].Xor(r[i-db+j], F.Mul(&t, c, v))
+		}
+	}
+	return q.trim(), r.trim()
+}
+
+// pmod returns a modulo b.
+func (F *Field) pmod(a, b fpoly) fpoly {
+	_, r := F.pdivmod(a, b)
+	return r
+}
+
+// pmonic returns a divided by its leading coefficient. Panics if a is zero.
+func (F *Field) pmonic(a fpoly) fpoly {
+	if len(a) == 0 {
+		panic("zero polynomial has no monic associate")
+	}
+	inv := F.Inv(new(big.Int), a[a.deg()])
+	r := make(fpoly, len(a))
+	for i, v := range a {
+		r[i] = F.Mul(new(big.Int), v, inv)
+	}
+	return r
+}
+
+// pgcd returns the monic GCD of a and b, or zero if both are zero.
+func (F *Field) pgcd(a, b fpoly) fpoly {
+	for len(b) != 0 {
+		a, b = b, F.pmod(a, b)
+	}
+	if len(a) == 0 {
+		return a
+	}
+	return F.pmonic(a)
+}
+
+// pfrob returns a^(2^k) modulo m.
+func (F *Field) pfrob(a, m fpoly, k int) fpoly {
+	for i := 0; i < k; i++ {
+		a = F.psqrmod(a, m)
+	}
+	return a
+}
+
+// psqrmod returns a^2 modulo m. Squaring only squares the coefficients and
+// spreads them out.
+func (F *Field) psqrmod(a, m fpoly) fpoly {
+	if len(a) == 0 {
+		return a
+	}
+	r := make(fpoly, 2*len(a)-1)
+	for i := range r {
+		if i%2 == 0 {
+			r[i] = F.Sqr(new(big.Int), a[i/2])
+		} else {
+			r[i] = new(big.Int)
+		}
+	}
+	return F.pmod(r, m)
+}
+
+// ptrace returns a + a^2 + a^4 + ... + a^(2^(k-1)) modulo m.
+func (F *Field) ptrace(a, m fpoly, k int) fpoly {
+	t := a
+	for i := 1; i < k; i++ {
+		a = F.psqrmod(a, m)
+		t = F.padd(t, a)
+	}
+	return t
+}
+
+// pderiv returns the formal derivative of a.
+func (F *Field) pderiv(a fpoly) fpoly {
+	if len(a) == 0 {
+		return nil
+	}
+	r := make(fpoly, len(a)-1)
+	for i := range r {
+		if i%2 == 0 {
+			r[i] = new(big.Int).Set(a[i+1])
+		} else {
+			r[i] = new(big.Int)
+		}
+	}
+	return r.trim()
+}
+
+// psqrt returns the square root of a, which must have zero derivative.
+func (F *Field) psqrt(a fpoly) fpoly {
+	r := make(fpoly, (len(a)+1)/2)
+	for i := range r {
+		r[i] = F.Sqrt(new(big.Int), a[2*i])
+	}
+	return r.trim()
+}
@@ -0,0 +1,154 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// evalIn evaluates the polynomial with coefficients c in F at z.
+func evalIn(F *Field, c []*big.Int, z *big.Int) *big.Int {
+	r := new(big.Int)
+	for i := len(c) - 1; i >= 0; i-- {
+		F.Mul(r, r, z)
+		F.Add(r, r, c[i])
+	}
+	return r
+}
+
+// mulIn multiplies two polynomials with coefficients in F.
+func mulIn(F *Field, a, b []*big.Int) []*big.Int {
+	r := make([]*big.Int, len(a)+len(b)-1)
+	for i := range r {
+		r[i] = new(big.Int)
+	}
+	var t big.Int
+	for i, x := range a {
+		for j, y := range b {
+			F.Add(r[i+j], r[i+j], F.Mul(&t, x, y))
+		}
+	}
+	return r
+}
+
+// coeffBits lists the coefficients of a GF(2)[x] polynomial.
+func coeffBits(f *big.Int) []*big.Int {
+	c := make([]*big.Int, f.BitLen())
+	for i := range c {
+		c[i] = big.NewInt(int64(f.Bit(i)))
+	}
+	return c
+}
+
+func TestRoots(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, F := range testFields() {
+		n := F.Degree()
+		for i := 0; i < 10; i++ {
+			f := randPoly(r, r.Intn(30)+1)
+			roots := F.Roots(f)
+			for k, z := range roots {
+				if v := evalIn(F, coeffBits(f), z); v.Sign() != 0 {
+					t.Errorf("GF(2^%d): %v is not a root of %v", n, z, f)
+				}
+				if k > 0 && roots[k-1].Cmp(z) >= 0 {
+					t.Errorf("GF(2^%d): roots of %v are not increasing", n, f)
+				}
+			}
+			if n > 8 {
+				continue
+			}
+			// Small fields can be searched exhaustively.
+			want := 0
+			for z := int64(0); z < 1<<uint(n); z++ {
+				if evalIn(F, coeffBits(f), big.NewInt(z)).Sign() == 0 {
+					want++
+				}
+			}
+			if len(roots) != want {
+				t.Errorf("GF(2^%d): %v has %d roots, want %d", n, f, len(roots), want)
+			}
+		}
+	}
+}
+
+func TestRootsOf(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, F := range testFields() {
+		n := F.Degree()
+		for i := 0; i < 10; i++ {
+			// Build a polynomial from distinct roots, with repeats.
+			set := make(map[string]*big.Int)
+			c := []*big.Int{big.NewInt(1)}
+			for k := r.Intn(6) + 1; k > 0; k-- {
+				z := randElem(r, F, false)
+				set[z.String()] = z
+				for e := r.Intn(2); e >= 0; e-- {
+					c = mulIn(F, c, []*big.Int{z, big.NewInt(1)})
+				}
+			}
+			roots := F.RootsOf(c)
+			if len(roots) != len(set) {
+				t.Errorf("GF(2^%d): found %d roots, want %d", n, len(roots), len(set))
+			}
+			for _, z := range roots {
+				if set[z.String()] == nil {
+					t.Errorf("GF(2^%d): %v is not one of the roots", n, z)
+				}
+			}
+		}
+	}
+}
+
+func TestFieldFactor(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, F := range testFields() {
+		n := F.Degree()
+		for i := 0; i < 10; i++ {
+			f := randPoly(r, r.Intn(25)+1)
+			prod := []*big.Int{big.NewInt(1)}
+			for _, p := range F.Factor(f) {
+				if p.P[len(p.P)-1].Cmp(big.NewInt(1)) != 0 {
+					t.Errorf("GF(2^%d): factor of %v is not monic", n, f)
+				}
+				for e := 0; e < p.E; e++ {
+					prod = mulIn(F, prod, p.P)
+				}
+			}
+			want := coeffBits(f)
+			if len(prod) != len(want) {
+				t.Errorf("GF(2^%d): factors of %v have degree %d", n, f, len(prod)-1)
+				continue
+			}
+			for k := range want {
+				if prod[k].Cmp(want[k]) != 0 {
+					t.Errorf("GF(2^%d): factors of %v multiply to something else", n, f)
+					break
+				}
+			}
+		}
+		// An irreducible polynomial of degree d over GF(2) splits into
+		// gcd(d, n) factors of degree d/gcd(d, n) over GF(2^n).
+		for _, p := range []int64{0x7, 0x13, 0x25, 0x43, 0x11b} {
+			d := PolyDeg(big.NewInt(p))
+			g := gcdInt(d, n)
+			fs := F.Factor(big.NewInt(p))
+			if len(fs) != g {
+				t.Errorf("GF(2^%d): %b has %d factors, want %d", n, p, len(fs), g)
+			}
+			for _, q := range fs {
+				if len(q.P)-1 != d/g || q.E != 1 {
+					t.Errorf("GF(2^%d): %b has factor of degree %d and multiplicity %d", n, p, len(q.P)-1, q.E)
+				}
+			}
+		}
+	}
+}
+
+// gcdInt returns the GCD of two positive integers.
+func gcdInt(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
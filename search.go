@@ -0,0 +1,222 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+// PolyIter iterates over the irreducible or primitive polynomials of a given
+// degree in increasing order. The number of candidates grows as 2^n, so this
+// is only practical for small degrees; use SearchTrinomials or
+// SearchPentanomials for large ones.
+type PolyIter struct {
+	// n is the degree and prim is whether to test primitivity.
+	n    int
+	prim bool
+	// p is the current polynomial and lim is the first polynomial of degree
+	// n+1.
+	p, lim *big.Int
+}
+
+// Irreducibles returns an iterator over the irreducible polynomials of degree
+// n, or over only the primitive polynomials if primitive is true. Panics if n
+// is not positive.
+func Irreducibles(n int, primitive bool) *PolyIter {
+	if n <= 0 {
+		panic(fmt.Sprintf("cannot enumerate polynomials of degree %d: degree must be positive", n))
+	}
+	it := PolyIter{
+		n:    n,
+		prim: primitive,
+		lim:  new(big.Int).Lsh(oneP, uint(n+1)),
+	}
+	return &it
+}
+
+// Next advances the iterator to the next polynomial and reports whether there
+// is one.
+func (it *PolyIter) Next() bool {
+	if it.p == nil {
+		it.p = new(big.Int).Lsh(oneP, uint(it.n))
+		if it.n == 1 && !it.prim {
+			// x is the only irreducible polynomial without a constant term.
+			return true
+		}
+		it.p.SetBit(it.p, 0, 1)
+	} else {
+		it.p = new(big.Int).Add(it.p, oneP)
+		// Skip to the next odd polynomial.
+		it.p.SetBit(it.p, 0, 1)
+	}
+	for ; it.p.Cmp(it.lim) < 0; it.p.Add(it.p, polyX) {
+		if it.test(it.p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Poly returns the current polynomial. It is not modified by later calls to
+// Next.
+func (it *PolyIter) Poly() *big.Int {
+	return it.p
+}
+
+// test reports whether p passes the iterator's test.
+func (it *PolyIter) test(p *big.Int) bool {
+	if it.prim {
+		return PolyPrimitive(p)
+	}
+	return PolyIrreducible(p)
+}
+
+// SearchOpts configures a search for low-weight irreducible polynomials. The
+// zero value searches for irreducible polynomials with no constraints.
+type SearchOpts struct {
+	// Primitive restricts the search to primitive polynomials. The search
+	// then factors 2^n - 1 for the degree n and panics if it cannot; see
+	// SetMersenneFactors.
+	Primitive bool
+	// Min and Max bound the exponents of the middle terms. If Min is less than
+	// one, it is treated as one. If Max is zero or not less than the degree, it
+	// is treated as the degree minus one.
+	Min, Max int
+	// Limit is the maximum number of results. Zero means no limit.
+	Limit int
+	// Workers is the number of goroutines to test candidates. Zero means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+}
+
+// SearchTrinomials finds the irreducible trinomials x^n + x^k + 1 subject to
+// the given options, which may be nil. Candidates are tested in parallel, but
+// the results are always in increasing order, and when the search is limited,
+// they are the first results in that order.
+func SearchTrinomials(n int, opts *SearchOpts) []*big.Int {
+	var o SearchOpts
+	if opts != nil {
+		o = *opts
+	}
+	lo, hi := o.bounds(n)
+	k := lo
+	next := func() *big.Int {
+		if k > hi {
+			return nil
+		}
+		p := new(big.Int).SetBit(oneP, n, 1)
+		p.SetBit(p, k, 1)
+		k++
+		return p
+	}
+	return search(n, next, &o)
+}
+
+// SearchPentanomials finds the irreducible pentanomials x^n + x^a + x^b +
+// x^c + 1, n > a > b > c > 0, subject to the given options, which may be nil.
+// The middle terms a, b, and c are all within the bounds set by the options.
+// Candidates are tested in parallel, but the results are always in increasing
+// order, and when the search is limited, they are the first results in that
+// order.
+func SearchPentanomials(n int, opts *SearchOpts) []*big.Int {
+	var o SearchOpts
+	if opts != nil {
+		o = *opts
+	}
+	lo, hi := o.bounds(n)
+	a, b, c := lo+2, lo+1, lo
+	next := func() *big.Int {
+		if a > hi {
+			return nil
+		}
+		p := new(big.Int).SetBit(oneP, n, 1)
+		p.SetBit(p, a, 1)
+		p.SetBit(p, b, 1)
+		p.SetBit(p, c, 1)
+		// Advance in increasing order of value: c fastest, then b, then a.
+		if c++; c == b {
+			c = lo
+			if b++; b == a {
+				b = lo + 1
+				a++
+			}
+		}
+		return p
+	}
+	return search(n, next, &o)
+}
+
+// bounds returns the effective bounds on middle terms for degree n. Panics if
+// n is too small to have any middle terms.
+func (o *SearchOpts) bounds(n int) (lo, hi int) {
+	if n < 2 {
+		panic(fmt.Sprintf("cannot search for polynomials of degree %d: degree must be at least 2", n))
+	}
+	lo, hi = o.Min, o.Max
+	if lo < 1 {
+		lo = 1
+	}
+	if hi <= 0 || hi >= n {
+		hi = n - 1
+	}
+	return lo, hi
+}
+
+// search tests the candidates of degree n produced by next, which returns nil
+// when there are no more, in batches across several goroutines. The results
+// are in the order that next produced them.
+func search(n int, next func() *big.Int, o *SearchOpts) []*big.Int {
+	workers := o.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	test := PolyIrreducible
+	if o.Primitive {
+		// Factor 2^n - 1 before starting the workers, so that they share the
+		// cached factors, and so that a failure panics in the caller rather
+		// than crashing the program from a worker.
+		mersenneFactors(n)
+		test = PolyPrimitive
+	}
+	var r []*big.Int
+	batch := make([]*big.Int, 0, 16*workers)
+	ok := make([]bool, cap(batch))
+	for {
+		batch = batch[:0]
+		for len(batch) < cap(batch) {
+			p := next()
+			if p == nil {
+				break
+			}
+			batch = append(batch, p)
+		}
+		if len(batch) == 0 {
+			return r
+		}
+		var wg sync.WaitGroup
+		ch := make(chan int, len(batch))
+		for i := range batch {
+			ch <- i
+		}
+		close(ch)
+		wg.Add(workers)
+		for w := 0; w < workers; w++ {
+			go func() {
+				defer wg.Done()
+				for i := range ch {
+					ok[i] = test(batch[i])
+				}
+			}()
+		}
+		wg.Wait()
+		for i, p := range batch {
+			if ok[i] {
+				r = append(r, p)
+				if len(r) == o.Limit {
+					return r
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package gof2
+
+import (
+	"math/big"
+)
+
+// Smith computes the Smith normal form of a matrix over GF(2)[x]. The
+// invariant factors d are the non-zero diagonal elements of the form, in
+// order, each dividing the next; there are as many as the rank of A. Every
+// non-zero polynomial over GF(2) is monic, so the invariant factors are
+// unique. Applied to CharMatrix(A), they are the similarity invariants of A,
+// the last being its minimal polynomial.
+//
+// If transforms is true, Smith also returns unimodular matrices U and V such
+// that U*A*V is the diagonal matrix of the invariant factors. Otherwise, U
+// and V are nil.
+func Smith(A M, transforms bool) (d []*big.Int, U, V *PFM) {
+	rows, cols := A.Size()
+	a := denseRows(A)
+	var u, v [][]*big.Int
+	if transforms {
+		u, v = eyeRows(rows), eyeRows(cols)
+	}
+	// swapCols exchanges columns i and j of a and v.
+	swapCols := func(i, j int) {
+		for _, row := range a {
+			row[i], row[j] = row[j], row[i]
+		}
+		for _, row := range v {
+			row[i], row[j] = row[j], row[i]
+		}
+	}
+	var q, r, t big.Int
+	for k := 0; k < rows && k < cols; k++ {
+		// Move an element of least degree to the pivot.
+		pi, pj := minDegree(a, k, k)
+		if pi < 0 {
+			break
+		}
+		a[k], a[pi] = a[pi], a[k]
+		if u != nil {
+			u[k], u[pi] = u[pi], u[k]
+		}
+		swapCols(k, pj)
+		for {
+			// Reduce the pivot's column and row by the pivot. Any remainder
+			// has lower degree than the pivot, so it becomes the new pivot.
+			done := true
+			for i := k + 1; i < rows; i++ {
+				if a[i][k].Sign() == 0 {
+					continue
+				}
+				PolyDivMod(&q, &r, a[i][k], a[k][k])
+				addRowMul(a[i], a[k], &q, &t)
+				if u != nil {
+					addRowMul(u[i], u[k], &q, &t)
+				}
+				if r.Sign() != 0 {
+					done = false
+				}
+			}
+			for j := k + 1; j < cols; j++ {
+				if a[k][j].Sign() == 0 {
+					continue
+				}
+				PolyDivMod(&q, &r, a[k][j], a[k][k])
+				addColMul(a, j, k, &q, &t)
+				if v != nil {
+					addColMul(v, j, k, &q, &t)
+				}
+				if r.Sign() != 0 {
+					done = false
+				}
+			}
+			if done {
+				// The pivot must also divide every remaining element. If it
+				// doesn't, adding that element's row to the pivot row puts a
+				// non-multiple in the pivot row for the next pass.
+				for i := k + 1; i < rows && done; i++ {
+					for j := k + 1; j < cols; j++ {
+						if PolyMod(&r, a[i][j], a[k][k]).Sign() != 0 {
+							addRowMul(a[k], a[i], oneP, &t)
+							if u != nil {
+								addRowMul(u[k], u[i], oneP, &t)
+							}
+							done = false
+							break
+						}
+					}
+				}
+				if done {
+					break
+				}
+			}
+			pi, pj := minDegreeCross(a, k)
+			a[k], a[pi] = a[pi], a[k]
+			if u != nil {
+				u[k], u[pi] = u[pi], u[k]
+			}
+			swapCols(k, pj)
+		}
+		d = append(d, new(big.Int).Set(a[k][k]))
+	}
+	if transforms {
+		U, V = pfmRows(u), pfmRows(v)
+	}
+	return d, U, V
+}
+
+// minDegree returns the position of a non-zero element of least degree in
+// the submatrix of a starting at row i and column j, or -1, -1 if the
+// submatrix is zero.
+func minDegree(a [][]*big.Int, i, j int) (int, int) {
+	bi, bj, bd := -1, -1, -1
+	for r := i; r < len(a); r++ {
+		for c := j; c < len(a[r]); c++ {
+			if d := PolyDeg(a[r][c]); d >= 0 && (bd < 0 || d < bd) {
+				bi, bj, bd = r, c, d
+			}
+		}
+	}
+	return bi, bj
+}
+
+// minDegreeCross returns the position of a non-zero element of least degree
+// in row k or column k of a, from the diagonal onward.
+func minDegreeCross(a [][]*big.Int, k int) (int, int) {
+	bi, bj, bd := k, k, PolyDeg(a[k][k])
+	for r := k; r < len(a); r++ {
+		if d := PolyDeg(a[r][k]); d >= 0 && d < bd {
+			bi, bj, bd = r, k, d
+		}
+	}
+	for c := k; c < len(a[k]); c++ {
+		if d := PolyDeg(a[k][c]); d >= 0 && d < bd {
+			bi, bj, bd = k, c, d
+		}
+	}
+	return bi, bj
+}
+
+// addRowMul adds q times row y to row x, using t as scratch space.
+func addRowMul(x, y []*big.Int, q, t *big.Int) {
+	for i, p := range y {
+		if p.Sign() != 0 {
+			x[i].Xor(x[i], PolyMul(t, q, p))
+		}
+	}
+}
+
+// addColMul adds q times column y of a to column x, using t as scratch space.
+func addColMul(a [][]*big.Int, x, y int, q, t *big.Int) {
+	for _, row := range a {
+		if row[y].Sign() != 0 {
+			row[x].Xor(row[x], PolyMul(t, q, row[y]))
+		}
+	}
+}
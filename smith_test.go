@@ -0,0 +1,96 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// diagPFM creates a rows x cols PFM with the elements of d on its diagonal.
+func diagPFM(rows, cols int, d []*big.Int) *PFM {
+	D := NewPFull(rows, cols)
+	for i, p := range d {
+		D.SetAt(i+1, i+1, p)
+	}
+	return D
+}
+
+func TestSmith(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	one := big.NewInt(1)
+	for _, sz := range [][2]int{{1, 1}, {2, 2}, {3, 3}, {5, 5}, {2, 5}, {5, 2}, {4, 6}, {8, 8}} {
+		for i := 0; i < 5; i++ {
+			A := randPFM(r, sz[0], sz[1], 3)
+			if i == 0 && sz[0] > 1 {
+				// Force a rank deficiency.
+				for j := 1; j <= sz[1]; j++ {
+					A.SetAt(sz[0], j, A.At(1, j))
+				}
+			}
+			d, U, V := Smith(A, true)
+			for k := 1; k < len(d); k++ {
+				if d[k-1].Sign() == 0 || PolyMod(new(big.Int), d[k], d[k-1]).Sign() != 0 {
+					t.Fatalf("invariant factor %v does not divide %v", d[k-1], d[k])
+				}
+			}
+			if !sameElements(PMul(PMul(U, A), V), diagPFM(sz[0], sz[1], d)) {
+				t.Errorf("U*A*V is not diagonal with invariants %v for %dx%d matrix", d, sz[0], sz[1])
+			}
+			if du, dv := Det(U), Det(V); du.Cmp(one) != 0 || dv.Cmp(one) != 0 {
+				t.Errorf("transforms of %dx%d matrix have determinants %v and %v, want 1", sz[0], sz[1], du, dv)
+			}
+			if sz[0] == sz[1] {
+				// The product of the invariants is the determinant, which
+				// is zero exactly when the rank is deficient.
+				p := big.NewInt(1)
+				if len(d) < sz[0] {
+					p.SetInt64(0)
+				}
+				for _, f := range d {
+					PolyMul(p, p, f)
+				}
+				if det := Det(A); p.Cmp(det) != 0 {
+					t.Errorf("product of invariants %v is %v, but determinant is %v", d, p, det)
+				}
+			}
+			e, U, V := Smith(A, false)
+			if U != nil || V != nil || len(e) != len(d) {
+				t.Fatalf("Smith without transforms gives %d invariants and %v, %v", len(e), U, V)
+			}
+			for k := range e {
+				if e[k].Cmp(d[k]) != 0 {
+					t.Errorf("Smith without transforms gives invariants %v, want %v", e, d)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestSmithCharMatrix(t *testing.T) {
+	// The similarity invariants of a companion matrix are 1, ..., 1, f.
+	r := rand.New(rand.NewSource(2))
+	for _, n := range []int{1, 3, 8, 20} {
+		f := randPoly(r, n)
+		f.SetBit(f, 0, 1)
+		d, _, _ := Smith(CharMatrix(Companion(f)), false)
+		if len(d) != n || d[n-1].Cmp(f) != 0 {
+			t.Errorf("invariants of companion of %v are %v", f, d)
+			continue
+		}
+		for _, p := range d[:n-1] {
+			if p.Cmp(big.NewInt(1)) != 0 {
+				t.Errorf("invariants of companion of %v are %v", f, d)
+				break
+			}
+		}
+	}
+	// The identity has invariants x+1 repeated.
+	d, _, _ := Smith(CharMatrix(Eye(4, 4)), false)
+	for _, p := range d {
+		if p.Cmp(big.NewInt(3)) != 0 || len(d) != 4 {
+			t.Errorf("invariants of I are %v, want x+1 four times", d)
+			break
+		}
+	}
+}
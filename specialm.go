@@ -3,6 +3,7 @@ package gof2
 import (
 	"fmt"
 	"math/big"
+	"sort"
 )
 
 // I is an immutable rectangular identity matrix defined such that I.At(r, c) is
@@ -95,11 +96,11 @@ func (rot R) At(r, c int) *big.Int {
 	if r <= 0 || r > rot.s || c <= 0 || c > rot.s {
 		panic(fmt.Sprintf("index (%d,%d) out of bounds (size %dx%d)", r, c, rot.s, rot.s))
 	}
-	r = (r-1+rot.n)%rot.s + 1
-	if r < 0 {
-		r += rot.s
+	c = (c - 1 + rot.n) % rot.s
+	if c < 0 {
+		c += rot.s
 	}
-	return to01(r == c)
+	return to01(r-1 == c)
 }
 
 // S is an immutable square matrix such that the multiplication X*S(n), n > 0
@@ -136,7 +137,75 @@ func (s S) At(r, c int) *big.Int {
 	if r <= 0 || r > s.s || c <= 0 || c > s.s {
 		panic(fmt.Sprintf("index (%d,%d) out of bounds (size %dx%d)", r, c, s.s, s.s))
 	}
-	return to01(r+s.n == c)
+	return to01(r == c+s.n)
+}
+
+// Comp is an immutable companion matrix of a polynomial f of degree n > 0: the
+// n x n matrix with ones on the principal subdiagonal and the coefficients of
+// 1, x, ..., x^(n-1) in f down its last column. It is the transition matrix
+// of the Galois LFSR with characteristic polynomial f; multiplying it by a
+// column vector holding the coefficients of a polynomial p gives x*p mod f.
+// Comp takes space proportional to the weight of f.
+type Comp struct {
+	immutableM
+	// n is the degree of the polynomial.
+	n int
+	// tail holds the exponents of the terms of f below x^n in increasing
+	// order.
+	tail []int
+}
+
+// Companion creates the companion matrix of a polynomial. Panics if f has
+// degree less than 1.
+func Companion(f *big.Int) Comp {
+	n := PolyDeg(f)
+	if n < 1 {
+		panic(fmt.Sprintf("can't create companion matrix of %s: degree must be positive", f.Text(2)))
+	}
+	var tail []int
+	forBits(f, func(i int) {
+		if i < n {
+			tail = append(tail, i)
+		}
+	})
+	return Comp{n: n, tail: tail}
+}
+
+// Size returns the size of the matrix.
+func (m Comp) Size() (rows, cols int) {
+	return m.n, m.n
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be modified.
+func (m Comp) At(r, c int) *big.Int {
+	if r <= 0 || r > m.n || c <= 0 || c > m.n {
+		panic(fmt.Sprintf("index (%d,%d) out of bounds (size %dx%d)", r, c, m.n, m.n))
+	}
+	if c < m.n {
+		return to01(r == c+1)
+	}
+	k := sort.SearchInts(m.tail, r-1)
+	return to01(k < len(m.tail) && m.tail[k] == r-1)
+}
+
+// Poly returns the polynomial of the companion matrix.
+func (m Comp) Poly() *big.Int {
+	f := new(big.Int).SetBit(new(big.Int), m.n, 1)
+	for _, i := range m.tail {
+		f.SetBit(f, i, 1)
+	}
+	return f
+}
+
+// forEach calls f with the zero-based row and column of each nonzero element.
+func (m Comp) forEach(f func(r, c int)) {
+	for c := 0; c < m.n-1; c++ {
+		f(c+1, c)
+	}
+	for _, r := range m.tail {
+		f(r, m.n-1)
+	}
 }
 
 type immutableM struct{}
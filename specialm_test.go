@@ -0,0 +1,225 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// sameElements reports whether two binary matrices have the same size and
+// elements.
+func sameElements(A, B M) bool {
+	ar, ac := A.Size()
+	br, bc := B.Size()
+	if ar != br || ac != bc {
+		return false
+	}
+	for r := 1; r <= ar; r++ {
+		for c := 1; c <= ac; c++ {
+			if A.At(r, c).Cmp(B.At(r, c)) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// randSparse creates a rows x cols SM with about n ones at random positions.
+func randSparse(r *rand.Rand, rows, cols, n int) *SM {
+	A := NewSparse(rows, cols)
+	for i := 0; i < n; i++ {
+		A.SetAt(r.Intn(rows)+1, r.Intn(cols)+1, big.NewInt(1))
+	}
+	return A
+}
+
+// checkMap checks that C has element (i, j) equal to element f(i, j) of X, or
+// zero where f returns ok false.
+func checkMap(t *testing.T, C, X M, f func(i, j int) (r, c int, ok bool)) {
+	t.Helper()
+	rows, cols := C.Size()
+	for i := 1; i <= rows; i++ {
+		for j := 1; j <= cols; j++ {
+			var want uint
+			if r, c, ok := f(i, j); ok {
+				want = X.At(r, c).Bit(0)
+			}
+			if got := C.At(i, j).Bit(0); got != want {
+				t.Fatalf("element (%d,%d) is %d, want %d", i, j, got, want)
+			}
+		}
+	}
+}
+
+// mod returns a mod n in [0, n).
+func mod(a, n int) int {
+	a %= n
+	if a < 0 {
+		a += n
+	}
+	return a
+}
+
+func TestRotation(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range []int{1, 2, 5, 8} {
+		for n := -2 * s; n <= 2*s; n++ {
+			R := Rol(s, n)
+			for r := 1; r <= s; r++ {
+				for c := 1; c <= s; c++ {
+					want := uint(0)
+					if mod(r-c-n, s) == 0 {
+						want = 1
+					}
+					if got := R.At(r, c).Bit(0); got != want {
+						t.Fatalf("Rol(%d, %d).At(%d, %d) = %d, want %d", s, n, r, c, got, want)
+					}
+				}
+			}
+			if !sameElements(Sparse(R), R) || !sameElements(Full(R), R) {
+				t.Errorf("conversions of Rol(%d, %d) disagree with At", s, n)
+			}
+			X := randSparse(rng, s, s, s)
+			// Each row of X*R(n) is the row of X rotated left n times.
+			left := func(i, j int) (int, int, bool) { return i, mod(j-1+n, s) + 1, true }
+			checkMap(t, FMul(X, R), X, left)
+			checkMap(t, FMul(Full(X), R), X, left)
+			// R(n)*X rotates the columns of X down n times.
+			down := func(i, j int) (int, int, bool) { return mod(i-1-n, s) + 1, j, true }
+			checkMap(t, FMul(R, X), X, down)
+			checkMap(t, FMul(R, Full(X)), X, down)
+		}
+	}
+}
+
+func TestShift(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, s := range []int{1, 2, 5, 8} {
+		for n := -s - 1; n <= s+1; n++ {
+			S := Shl(s, n)
+			for r := 1; r <= s; r++ {
+				for c := 1; c <= s; c++ {
+					want := uint(0)
+					if r == c+n {
+						want = 1
+					}
+					if got := S.At(r, c).Bit(0); got != want {
+						t.Fatalf("Shl(%d, %d).At(%d, %d) = %d, want %d", s, n, r, c, got, want)
+					}
+				}
+			}
+			if !sameElements(Sparse(S), S) || !sameElements(Full(S), S) {
+				t.Errorf("conversions of Shl(%d, %d) disagree with At", s, n)
+			}
+			X := randSparse(rng, s, s, s)
+			// Each row of X*S(n) is the row of X shifted left n times.
+			left := func(i, j int) (int, int, bool) { return i, j + n, j+n >= 1 && j+n <= s }
+			checkMap(t, FMul(X, S), X, left)
+			checkMap(t, FMul(Full(X), S), X, left)
+			down := func(i, j int) (int, int, bool) { return i - n, j, i-n >= 1 && i-n <= s }
+			checkMap(t, FMul(S, X), X, down)
+			checkMap(t, FMul(S, Full(X)), X, down)
+		}
+	}
+}
+
+// TestShiftHighRows checks products of R and S with sparse matrices having
+// nonzero elements in rows that need all 16 bits of the row index.
+func TestShiftHighRows(t *testing.T) {
+	const s = 5000
+	rng := rand.New(rand.NewSource(3))
+	X := NewSparse(s, 3)
+	for i := 0; i < 20; i++ {
+		X.SetAt(s-rng.Intn(900), rng.Intn(3)+1, big.NewInt(1))
+	}
+	for _, n := range []int{-7, -1, 1, 7} {
+		checkMap(t, FMul(Rol(s, n), X), X, func(i, j int) (int, int, bool) {
+			return mod(i-1-n, s) + 1, j, true
+		})
+		checkMap(t, FMul(Shl(s, n), X), X, func(i, j int) (int, int, bool) {
+			return i - n, j, i-n >= 1 && i-n <= s
+		})
+	}
+}
+
+func TestEyeRectangular(t *testing.T) {
+	for _, sz := range [][2]int{{3, 5}, {5, 3}, {1, 4}, {4, 4}} {
+		E := Eye(sz[0], sz[1])
+		if !sameElements(Sparse(E), E) || !sameElements(Full(E), E) {
+			t.Errorf("conversions of Eye(%d, %d) disagree with At", sz[0], sz[1])
+		}
+	}
+}
+
+func TestCompanion(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for _, n := range []int{1, 2, 7, 63, 64, 65, 130} {
+		f := randPoly(r, n)
+		C := Companion(f)
+		if C.Poly().Cmp(f) != 0 {
+			t.Errorf("Poly of companion of %v is %v", f, C.Poly())
+		}
+		for i := 1; i <= n; i++ {
+			for j := 1; j <= n; j++ {
+				want := uint(0)
+				if j < n && i == j+1 || j == n && f.Bit(i-1) != 0 {
+					want = 1
+				}
+				if got := C.At(i, j).Bit(0); got != want {
+					t.Fatalf("companion of %v has element (%d,%d) = %d, want %d", f, i, j, got, want)
+				}
+			}
+		}
+		F := Full(C)
+		if !sameElements(Sparse(C), F) || !sameElements(Dense(C), F) || !sameElements(LSparse(C), F) || !sameElements(LFull(C), F) {
+			t.Errorf("conversions of companion of degree %d disagree", n)
+		}
+		// Multiplying by a polynomial's coefficients multiplies it by x.
+		m := NewModulus(f)
+		p := randPoly(r, r.Intn(n))
+		if got, want := MulVec(C, p), m.Mul(new(big.Int), p, big.NewInt(2)); got.Cmp(want) != 0 {
+			t.Errorf("companion of %v times %v is %v, want %v", f, p, got, want)
+		}
+		checkMulVec(t, r, "Comp", C)
+		for _, k := range []int{1, 5, 70} {
+			X := randSparse(r, n, k, n*k/4)
+			Y := randSparse(r, k, n, n*k/4)
+			for _, B := range []M{X, Full(X), Dense(X)} {
+				if !sameElements(FMul(C, B), fMulNaive(C, B)) {
+					t.Errorf("companion of degree %d times %T is wrong", n, B)
+				}
+			}
+			for _, A := range []M{Y, Full(Y), Dense(Y)} {
+				if !sameElements(FMul(A, C), fMulNaive(A, C)) {
+					t.Errorf("%T times companion of degree %d is wrong", A, n)
+				}
+			}
+		}
+	}
+}
+
+func TestEyeRectangularMul(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for _, sz := range [][2]int{{3, 5}, {5, 3}, {4, 4}} {
+		E := Eye(sz[0], sz[1])
+		X := randSparse(r, sz[1], 4, 8)
+		Y := randSparse(r, 4, sz[0], 8)
+		// I(m, n) keeps the first min(m, n) rows or columns and pads with zeros.
+		rows := func(i, j int) (int, int, bool) { return i, j, i <= sz[1] }
+		cols := func(i, j int) (int, int, bool) { return i, j, j <= sz[0] }
+		for _, B := range []M{X, Full(X)} {
+			C := FMul(E, B)
+			if cr, cc := C.Size(); cr != sz[0] || cc != 4 {
+				t.Fatalf("Eye(%d, %d) times %T has size %dx%d", sz[0], sz[1], B, cr, cc)
+			}
+			checkMap(t, C, X, rows)
+		}
+		for _, A := range []M{Y, Full(Y)} {
+			C := FMul(A, E)
+			if cr, cc := C.Size(); cr != 4 || cc != sz[1] {
+				t.Fatalf("%T times Eye(%d, %d) has size %dx%d", A, sz[0], sz[1], cr, cc)
+			}
+			checkMap(t, C, Y, cols)
+		}
+	}
+}
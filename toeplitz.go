@@ -0,0 +1,191 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Toep is an immutable Toeplitz matrix, constant along each diagonal. It is
+// stored as a single polynomial holding its first row and column, so it takes
+// space proportional to its dimensions. Multiplying it by a vector is a
+// polynomial multiplication; Toeplitz hashing of an n-bit string to m bits is
+// MulVec with an m x n Toep.
+type Toep struct {
+	immutableM
+	// r and c are the size of the matrix.
+	r, c int
+	// t holds the diagonals. Bit i-j+c-1 is the element in zero-based row i
+	// and column j.
+	t *big.Int
+}
+
+// Toeplitz creates a Toeplitz matrix from its first column and first row as
+// vectors, with bit i of each holding the element at one-based index i+1.
+// Panics if either size is non-positive or too large, if either vector has
+// bits at or above its length, or if the vectors differ in their first
+// element.
+func Toeplitz(col, row *big.Int, rows, cols int) Toep {
+	checkLarge(rows, cols, false)
+	if col.Sign() < 0 || col.BitLen() > rows || row.Sign() < 0 || row.BitLen() > cols {
+		panic(fmt.Sprintf("can't create %dx%d Toep from column of length %d and row of length %d", rows, cols, col.BitLen(), row.BitLen()))
+	}
+	if col.Bit(0) != row.Bit(0) {
+		panic("can't create Toep: first column and first row disagree in the first element")
+	}
+	t := new(big.Int).Lsh(col, uint(cols-1))
+	t.Xor(t, polyRev(row, cols-1))
+	t.SetBit(t, cols-1, row.Bit(0))
+	return Toep{r: rows, c: cols, t: t}
+}
+
+// Size returns the size of the matrix.
+func (m Toep) Size() (rows, cols int) {
+	return m.r, m.c
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be modified.
+func (m Toep) At(r, c int) *big.Int {
+	if r <= 0 || r > m.r || c <= 0 || c > m.c {
+		panic(fmt.Sprintf("index (%d,%d) out of bounds (size %dx%d)", r, c, m.r, m.c))
+	}
+	return to01(m.t.Bit(r-c+m.c-1) != 0)
+}
+
+// Col returns the first column of the matrix as a new vector.
+func (m Toep) Col() *big.Int {
+	return m.column(0)
+}
+
+// Row returns the first row of the matrix as a new vector.
+func (m Toep) Row() *big.Int {
+	return polyRev(m.t, m.c-1)
+}
+
+// Transpose returns the transpose of the matrix, which is also Toeplitz.
+func (m Toep) Transpose() Toep {
+	return Toep{r: m.c, c: m.r, t: polyRev(m.t, m.r+m.c-2)}
+}
+
+// column returns the column at a zero-based index as a new vector.
+func (m Toep) column(j int) *big.Int {
+	y := new(big.Int).Rsh(m.t, uint(m.c-1-j))
+	return polyTrunc(y, y, m.r)
+}
+
+// mulVec returns the product of the matrix by a column vector, which is a
+// window of the product of the diagonals with x as polynomials.
+func (m Toep) mulVec(x *big.Int) *big.Int {
+	y := PolyMul(new(big.Int), m.t, x)
+	y.Rsh(y, uint(m.c-1))
+	return polyTrunc(y, y, m.r)
+}
+
+// forEach calls f with the zero-based row and column of each nonzero element.
+func (m Toep) forEach(f func(r, c int)) {
+	for j := 0; j < m.c; j++ {
+		forBits(m.column(j), func(i int) { f(i, j) })
+	}
+}
+
+// Circ is an immutable n x n circulant matrix, in which each column is the one
+// before it rotated down by one. It is stored as its first column, and
+// multiplying it by a vector is a polynomial multiplication modulo x^n - 1.
+// R(n) is the circulant whose first column has the single element n+1.
+type Circ struct {
+	immutableM
+	// n is the size of the matrix.
+	n int
+	// col is the first column.
+	col *big.Int
+}
+
+// Circulant creates an n x n circulant matrix from its first column as a
+// vector, with bit i holding the element at one-based index i+1. Panics if n
+// is non-positive or too large or if the vector has bits at or above n.
+func Circulant(col *big.Int, n int) Circ {
+	checkLarge(n, n, false)
+	if col.Sign() < 0 || col.BitLen() > n {
+		panic(fmt.Sprintf("can't create %dx%d Circ from column of length %d", n, n, col.BitLen()))
+	}
+	return Circ{n: n, col: new(big.Int).Set(col)}
+}
+
+// Size returns the size of the matrix.
+func (m Circ) Size() (rows, cols int) {
+	return m.n, m.n
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be modified.
+func (m Circ) At(r, c int) *big.Int {
+	if r <= 0 || r > m.n || c <= 0 || c > m.n {
+		panic(fmt.Sprintf("index (%d,%d) out of bounds (size %dx%d)", r, c, m.n, m.n))
+	}
+	k := r - c
+	if k < 0 {
+		k += m.n
+	}
+	return to01(m.col.Bit(k) != 0)
+}
+
+// Col returns the first column of the matrix as a new vector.
+func (m Circ) Col() *big.Int {
+	return new(big.Int).Set(m.col)
+}
+
+// Transpose returns the transpose of the matrix, which is also circulant.
+func (m Circ) Transpose() Circ {
+	t := new(big.Int)
+	forBits(m.col, func(i int) {
+		t.SetBit(t, (m.n-i)%m.n, 1)
+	})
+	return Circ{n: m.n, col: t}
+}
+
+// Compose returns the product m*q, which is also circulant. Panics if the
+// matrices have different sizes.
+func (m Circ) Compose(q Circ) Circ {
+	if m.n != q.n {
+		panic(fmt.Sprintf("inner dimension mismatch: %dx%d * %dx%d", m.n, m.n, q.n, q.n))
+	}
+	return Circ{n: m.n, col: m.mulVec(q.col)}
+}
+
+// column returns the column at a zero-based index as a new vector.
+func (m Circ) column(j int) *big.Int {
+	return m.fold(new(big.Int).Lsh(m.col, uint(j)))
+}
+
+// fold reduces a polynomial of degree less than 2n modulo x^n - 1 in place.
+func (m Circ) fold(y *big.Int) *big.Int {
+	var hi big.Int
+	hi.Rsh(y, uint(m.n))
+	polyTrunc(y, y, m.n)
+	return y.Xor(y, &hi)
+}
+
+// mulVec returns the product of the matrix by a column vector.
+func (m Circ) mulVec(x *big.Int) *big.Int {
+	return m.fold(PolyMul(new(big.Int), m.col, x))
+}
+
+// forEach calls f with the zero-based row and column of each nonzero element.
+func (m Circ) forEach(f func(r, c int)) {
+	for j := 0; j < m.n; j++ {
+		forBits(m.column(j), func(i int) { f(i, j) })
+	}
+}
+
+// columnBits returns the words of the column-major bit vector of a full matrix
+// with the given columns, including the sentinel bit.
+func columnBits(rows, cols int, column func(j int) *big.Int) []big.Word {
+	const w = bits.UintSize
+	v := make([]big.Word, rows*cols/w+1)
+	for j := 0; j < cols; j++ {
+		orBitRange(v, column(j).Bits(), j*rows)
+	}
+	v[rows*cols/w] |= 1 << uint(rows*cols%w)
+	return v
+}
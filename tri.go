@@ -0,0 +1,308 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// TM is a square upper or lower triangular binary matrix storing only its
+// triangle. Rows are packed into 64-bit words like those of DM, but each row
+// keeps only the words that meet the triangle, so row operations stay aligned
+// with DM and the matrix takes about half the space.
+type TM struct {
+	n int
+	// upper is whether the matrix is upper triangular.
+	upper bool
+	// stride is the number of words in a full row.
+	stride int
+	// v holds the stored words of each row in order. Row i begins at word
+	// start[i]. The first stored word of a row of an upper triangular matrix
+	// holds columns from 64*(i/64), and that of a lower triangular matrix
+	// holds columns from 0. Bits outside the triangle are always zero.
+	v     []uint64
+	start []int
+}
+
+// NewTriangular creates an n x n zero matrix that is upper triangular if upper
+// is true and lower triangular otherwise. Panics if n is non-positive or if
+// the matrix has too many elements.
+func NewTriangular(n int, upper bool) *TM {
+	checkLarge(n, n, true)
+	stride := (n + 63) / 64
+	start := make([]int, n+1)
+	for i := 0; i < n; i++ {
+		if upper {
+			start[i+1] = start[i] + stride - i/64
+		} else {
+			start[i+1] = start[i] + i/64 + 1
+		}
+	}
+	return &TM{n: n, upper: upper, stride: stride, v: make([]uint64, start[n]), start: start}
+}
+
+// Triangular converts any type of square binary matrix to a new triangular
+// matrix. Only elements within the triangle are read; all others are treated
+// as zero. Types TM and DM are special-cased. All other types are filled in
+// O(n^2) time. Panics if m is not square or if any element in the triangle is
+// not 0 or 1.
+func Triangular(m M, upper bool) *TM {
+	m = unwrapAdaptive(m)
+	rows, cols := m.Size()
+	if rows != cols {
+		panic(fmt.Sprintf("cannot make triangular matrix from %dx%d matrix: matrix must be square", rows, cols))
+	}
+	T := NewTriangular(rows, upper)
+	switch A := m.(type) {
+	case *TM:
+		if A.upper == upper {
+			copy(T.v, A.v)
+			break
+		}
+		// Only the principal diagonal is shared.
+		for i := 0; i < T.n; i++ {
+			if A.has(i, i) {
+				T.set(i, i)
+			}
+		}
+	case *DM:
+		for i := 0; i < T.n; i++ {
+			w, f := T.row(i)
+			copy(w, A.row(i)[f:])
+			T.mask(i)
+		}
+	default:
+		for i := 0; i < T.n; i++ {
+			for j := 0; j < T.n; j++ {
+				if T.in(i, j) && check01(m.At(i+1, j+1)) != 0 {
+					T.set(i, j)
+				}
+			}
+		}
+	}
+	return T
+}
+
+// Size returns the size of the matrix.
+func (T *TM) Size() (rows, cols int) {
+	return T.n, T.n
+}
+
+// Upper returns whether the matrix is upper triangular.
+func (T *TM) Upper() bool {
+	return T.upper
+}
+
+// At returns a polynomial containing the element at the given one-based row
+// and column. The returned value is a shared constant and must not be
+// modified.
+func (T *TM) At(r, c int) *big.Int {
+	T.check(r, c)
+	return to01(T.in(r-1, c-1) && T.has(r-1, c-1))
+}
+
+// SetAt sets the element at the given one-based row and column. Panics if the
+// index is out of bounds, if p is not 0 or 1, or if p is 1 and the index is
+// outside the triangle.
+func (T *TM) SetAt(r, c int, p *big.Int) {
+	T.check(r, c)
+	b := check01(p)
+	if !T.in(r-1, c-1) {
+		if b != 0 {
+			panic(fmt.Sprintf("cannot set (%d,%d) outside triangle of %dx%d matrix", r, c, T.n, T.n))
+		}
+		return
+	}
+	k, m := T.word(r-1, c-1)
+	if b != 0 {
+		T.v[k] |= m
+	} else {
+		T.v[k] &^= m
+	}
+}
+
+// AddAt adds to the element at the given one-based row and column. Panics if
+// the index is out of bounds, if p is not 0 or 1, or if p is 1 and the index
+// is outside the triangle.
+func (T *TM) AddAt(r, c int, p *big.Int) *big.Int {
+	T.check(r, c)
+	b := check01(p)
+	if !T.in(r-1, c-1) {
+		if b != 0 {
+			panic(fmt.Sprintf("cannot add to (%d,%d) outside triangle of %dx%d matrix", r, c, T.n, T.n))
+		}
+		return zeroP
+	}
+	k, m := T.word(r-1, c-1)
+	if b != 0 {
+		T.v[k] ^= m
+	}
+	return to01(T.v[k]&m != 0)
+}
+
+// MulAt multiplies the element at the given one-based row and column. Panics
+// if the index is out of bounds or if p is not 0 or 1.
+func (T *TM) MulAt(r, c int, p *big.Int) *big.Int {
+	T.check(r, c)
+	b := check01(p)
+	if !T.in(r-1, c-1) {
+		return zeroP
+	}
+	k, m := T.word(r-1, c-1)
+	if b == 0 {
+		T.v[k] &^= m
+	}
+	return to01(T.v[k]&m != 0)
+}
+
+// Solve returns the vector x such that T*x = b, using back substitution if T
+// is upper triangular and forward substitution otherwise. Each step takes the
+// inner product of a stored row with the solved part of x a word at a time.
+// Returns nil if T is singular, i.e. if any element of its principal diagonal
+// is zero. Panics if b has bits at or above the size of T.
+func (T *TM) Solve(b *big.Int) *big.Int {
+	if b.Sign() < 0 || b.BitLen() > T.n {
+		panic(fmt.Sprintf("cannot solve %dx%d matrix with vector of length %d", T.n, T.n, b.BitLen()))
+	}
+	bw := words64(b, T.n)
+	x := make([]uint64, T.stride)
+	for s := 0; s < T.n; s++ {
+		i := s
+		if T.upper {
+			i = T.n - 1 - s
+		}
+		w, f := T.row(i)
+		k, m := i/64, uint64(1)<<uint(i%64)
+		if w[k-f]&m == 0 {
+			return nil
+		}
+		// The element of x at i is still zero, so the diagonal doesn't
+		// contribute to the inner product.
+		var p uint64
+		for j, y := range w {
+			p ^= y & x[f+j]
+		}
+		if (bits.OnesCount64(p)&1 != 0) != (bw[k]&m != 0) {
+			x[k] |= m
+		}
+	}
+	return bigWords64(x)
+}
+
+// row returns the stored words of the row at a zero-based index and the index
+// of the first of them within a full row.
+func (T *TM) row(i int) ([]uint64, int) {
+	w := T.v[T.start[i]:T.start[i+1]:T.start[i+1]]
+	if T.upper {
+		return w, i / 64
+	}
+	return w, 0
+}
+
+// in returns whether the zero-based row and column are within the triangle.
+func (T *TM) in(r, c int) bool {
+	if T.upper {
+		return c >= r
+	}
+	return c <= r
+}
+
+// has returns whether the element at a zero-based row and column within the
+// triangle is set.
+func (T *TM) has(r, c int) bool {
+	k, m := T.word(r, c)
+	return T.v[k]&m != 0
+}
+
+// set sets the element at a zero-based row and column within the triangle.
+func (T *TM) set(r, c int) {
+	k, m := T.word(r, c)
+	T.v[k] |= m
+}
+
+// word returns the index in v and the bit mask of the element at a zero-based
+// row and column within the triangle.
+func (T *TM) word(r, c int) (int, uint64) {
+	_, f := T.row(r)
+	return T.start[r] + c/64 - f, 1 << uint(c%64)
+}
+
+// mask clears the bits of the stored words of a row that are outside the
+// triangle.
+func (T *TM) mask(i int) {
+	w, f := T.row(i)
+	k := i/64 - f
+	if T.upper {
+		w[k] &^= 1<<uint(i%64) - 1
+	} else {
+		w[k] &= 1<<uint(i%64)<<1 - 1
+	}
+}
+
+// check panics if the given one-based row or column index is out of bounds.
+func (T *TM) check(r, c int) {
+	if r <= 0 || r > T.n {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r, T.n, T.n))
+	}
+	if c <= 0 || c > T.n {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c, T.n, T.n))
+	}
+}
+
+// forEach calls f with the zero-based row and column of each set element in
+// row-major order.
+func (T *TM) forEach(f func(r, c int)) {
+	for i := 0; i < T.n; i++ {
+		w, first := T.row(i)
+		for k, x := range w {
+			for x != 0 {
+				f(i, (first+k)*64+bits.TrailingZeros64(x))
+				x &= x - 1
+			}
+		}
+	}
+}
+
+// dense copies the matrix into a new DM.
+func (T *TM) dense() *DM {
+	B := NewDense(T.n, T.n)
+	for i := 0; i < T.n; i++ {
+		w, f := T.row(i)
+		copy(B.row(i)[f:], w)
+	}
+	return B
+}
+
+// mulVec returns the product of T by a column vector.
+func (T *TM) mulVec(x *big.Int) *big.Int {
+	xw := words64(x, T.n)
+	y := make([]uint64, T.stride)
+	for i := 0; i < T.n; i++ {
+		w, f := T.row(i)
+		var p uint64
+		for j, v := range w {
+			p ^= v & xw[f+j]
+		}
+		y[i/64] |= uint64(bits.OnesCount64(p)&1) << uint(i%64)
+	}
+	return bigWords64(y)
+}
+
+// fMulTri multiplies two triangular matrices of the same orientation into a
+// new TM. Each row of B added into the product starts no earlier than the row
+// of the product does, so only stored words are touched.
+func fMulTri(A, B *TM) *TM {
+	C := NewTriangular(A.n, A.upper)
+	for i := 0; i < A.n; i++ {
+		aw, f := A.row(i)
+		cw, _ := C.row(i)
+		for k, x := range aw {
+			for x != 0 {
+				bw, g := B.row((f+k)*64 + bits.TrailingZeros64(x))
+				xorRow(cw[g-f:], bw)
+				x &= x - 1
+			}
+		}
+	}
+	return C
+}
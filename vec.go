@@ -0,0 +1,176 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// MulVec multiplies a binary matrix by a column vector, returning a new
+// vector. Vectors are represented as big ints, with bit i holding the element
+// at one-based index i+1. Types SM, FM, LSM, LFM, DM, CSR, CSC, BM, TM,
+// Toep, Circ, I, Z, R, S, Comp, and Perm are special-cased, Sum, Product, and
+// untransposed V multiply through what they refer to without being evaluated,
+// and all other types use O(mn) calls to At. Panics if x has bits at or above
+// the number of columns of A or if any element of A is not 0 or 1.
+func MulVec(A M, x *big.Int) *big.Int {
+	rows, cols := A.Size()
+	if x.BitLen() > cols {
+		panic(fmt.Sprintf("cannot multiply %dx%d matrix by vector of length %d", rows, cols, x.BitLen()))
+	}
+	y := new(big.Int)
+	switch X := unwrapAdaptive(A).(type) {
+	case *SM:
+		for k, v := range X.v {
+			if v != 0 && x.Bit(int(k>>16)) != 0 {
+				r := int(k & 0xffff)
+				y.SetBit(y, r, y.Bit(r)^1)
+			}
+		}
+	case *LSM:
+		for k, v := range X.v {
+			if v != 0 && x.Bit(int(k>>32)) != 0 {
+				r := int(k & 0xffffffff)
+				y.SetBit(y, r, y.Bit(r)^1)
+			}
+		}
+	case *FM:
+		mulVecFull(y, X.v, rows, x)
+	case *LFM:
+		mulVecFull(y, X.v, rows, x)
+	case *DM:
+		xw := words64(x, cols)
+		for r := 0; r < rows; r++ {
+			var p uint64
+			for k, w := range X.row(r) {
+				p ^= w & xw[k]
+			}
+			if bits.OnesCount64(p)&1 != 0 {
+				y.SetBit(y, r, 1)
+			}
+		}
+	case *CSR:
+		for r := 0; r < rows; r++ {
+			var p uint
+			for _, c := range X.s.line(r) {
+				p ^= x.Bit(int(c))
+			}
+			y.SetBit(y, r, p)
+		}
+	case *CSC:
+		forBits(x, func(c int) {
+			for _, r := range X.s.line(c) {
+				y.SetBit(y, int(r), y.Bit(int(r))^1)
+			}
+		})
+	case Comp:
+		// Multiply by x modulo the polynomial.
+		y.Lsh(x, 1)
+		if y.Bit(X.n) != 0 {
+			y.SetBit(y, X.n, 0)
+			for _, t := range X.tail {
+				y.SetBit(y, t, y.Bit(t)^1)
+			}
+		}
+	case *BM:
+		y = X.mulVec(x)
+	case *TM:
+		y = X.mulVec(x)
+	case Toep:
+		y = X.mulVec(x)
+	case Circ:
+		y = X.mulVec(x)
+	case Sum:
+		y = X.mulVec(x)
+	case Product:
+		y = X.mulVec(x)
+	case V:
+		y = X.mulVec(x)
+	case Perm:
+		forBits(x, func(c int) { y.SetBit(y, X.p[c], 1) })
+	case I:
+		polyTrunc(y, x, rows)
+	case Z:
+		// do nothing
+	case R:
+		// R moves element c to row c+n mod s.
+		y.Lsh(x, uint(X.n+X.s)%uint(X.s))
+		var hi big.Int
+		hi.Rsh(y, uint(X.s))
+		polyTrunc(y, y, X.s)
+		y.Xor(y, &hi)
+	case S:
+		if X.n >= 0 {
+			y.Lsh(x, uint(X.n))
+		} else {
+			y.Rsh(x, uint(-X.n))
+		}
+		polyTrunc(y, y, rows)
+	default:
+		forBits(x, func(c int) {
+			for r := 0; r < rows; r++ {
+				if check01(A.At(r+1, c+1)) != 0 {
+					y.SetBit(y, r, y.Bit(r)^1)
+				}
+			}
+		})
+	}
+	return y
+}
+
+// vecMuler is a matrix type that multiplies column vectors without going
+// through its elements.
+type vecMuler interface {
+	M
+	mulVec(x *big.Int) *big.Int
+}
+
+// mulVecFull sets y to the product of a full matrix with the given column-major
+// bit vector and number of rows by x.
+func mulVecFull(y, v *big.Int, rows int, x *big.Int) {
+	w := make([]big.Word, (rows+bits.UintSize-1)/bits.UintSize)
+	src := v.Bits()
+	forBits(x, func(c int) {
+		xorBitRange(w, src, c*rows)
+	})
+	polyTrunc(y, y.SetBits(w), rows)
+}
+
+// forBits calls f with the index of each set bit of x in increasing order.
+func forBits(x *big.Int, f func(int)) {
+	for i, w := range x.Bits() {
+		for w != 0 {
+			f(i*bits.UintSize + bits.TrailingZeros(uint(w)))
+			w &= w - 1
+		}
+	}
+}
+
+// xorBitRange adds into dst the bits of src starting at bit off.
+func xorBitRange(dst, src []big.Word, off int) {
+	const w = bits.UintSize
+	q, s := off/w, uint(off%w)
+	for i := range dst {
+		var lo, hi big.Word
+		if q+i < len(src) {
+			lo = src[q+i] >> s
+		}
+		if s != 0 && q+i+1 < len(src) {
+			hi = src[q+i+1] << (w - s)
+		}
+		dst[i] ^= lo | hi
+	}
+}
+
+// dotVec returns the inner product over GF(2) of two vectors.
+func dotVec(x, y *big.Int) uint {
+	xw, yw := x.Bits(), y.Bits()
+	if len(yw) < len(xw) {
+		xw = xw[:len(yw)]
+	}
+	var p uint
+	for i, w := range xw {
+		p ^= uint(w & yw[i])
+	}
+	return uint(bits.OnesCount(p)) & 1
+}
@@ -0,0 +1,82 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// mulVecNaive multiplies A by x using At.
+func mulVecNaive(A M, x *big.Int) *big.Int {
+	rows, cols := A.Size()
+	y := new(big.Int)
+	for r := 1; r <= rows; r++ {
+		var b uint
+		for c := 1; c <= cols; c++ {
+			b ^= A.At(r, c).Bit(0) & x.Bit(c-1)
+		}
+		y.SetBit(y, r-1, b)
+	}
+	return y
+}
+
+// namedM is a matrix with a name for test messages.
+type namedM struct {
+	name string
+	m    M
+}
+
+// checkMulVec checks MulVec(A, x) against mulVecNaive for random x.
+func checkMulVec(t *testing.T, r *rand.Rand, name string, A M) {
+	t.Helper()
+	_, cols := A.Size()
+	for i := 0; i < 5; i++ {
+		x := randBits(r, cols)
+		if got, want := MulVec(A, x), mulVecNaive(A, x); got.Cmp(want) != 0 {
+			t.Errorf("%s: MulVec(A, %v) = %v, want %v", name, x, got, want)
+		}
+	}
+}
+
+func TestMulVec(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sz := range [][2]int{{1, 1}, {5, 9}, {64, 64}, {70, 130}} {
+		n, m := sz[0], sz[1]
+		S := randSparse(r, n, m, n*m/4)
+		band := NewBanded(n, m, 2, 1)
+		for i := 1; i <= n && i <= m; i++ {
+			band.SetAt(i, i, big.NewInt(1))
+			if i > 2 {
+				band.SetAt(i, i-2, big.NewInt(int64(r.Intn(2))))
+			}
+		}
+		row := randBits(r, m)
+		col := randBits(r, n)
+		row.SetBit(row, 0, col.Bit(0))
+		cases := []namedM{
+			{"SM", S},
+			{"FM", Full(S)},
+			{"I", Eye(n, m)},
+			{"Z", Zeros(n, m)},
+			{"BM", band},
+			{"Toep", Toeplitz(col, row, n, m)},
+		}
+		if n == m {
+			p := r.Perm(n)
+			for i := range p {
+				p[i]++
+			}
+			f := randPoly(r, n)
+			cases = append(cases, []namedM{
+				{"R", Rol(n, r.Intn(2*n+1)-n)},
+				{"S", Shl(n, r.Intn(2*n+1)-n)},
+				{"Circ", Circulant(col, n)},
+				{"Comp", Companion(f)},
+				{"Perm", Permutation(p)},
+			}...)
+		}
+		for _, c := range cases {
+			checkMulVec(t, r, c.name, c.m)
+		}
+	}
+}
@@ -5,67 +5,317 @@ import (
 	"math/big"
 )
 
-// V provides a submatrix view. It proxies methods to its matrix offset by the
-// view area.
+// V provides a view of a matrix: a selection of its rows and columns, possibly
+// transposed. Reads and writes through the view go to the viewed matrix, so a
+// view of a mutable matrix is writable. Every constructor checks that the view
+// lies within the bounds of the viewed matrix, so each index inside the view
+// maps to a valid index of the matrix, and accesses outside the view's own
+// bounds panic. A view of a view is collapsed into a single view of the
+// underlying matrix.
+//
+// Views may have zero rows or columns. Such a view has no elements, and since
+// other matrix types must have positive size, it cannot be converted or
+// multiplied.
 type V struct {
 	m M
-	// r, c are the view's row and column offsets into the parent matrix.
-	r, c int
-	// rr, cc are the view's size.
-	rr, cc int
+	// rows and cols map the view's zero-based rows and columns to zero-based
+	// indices of m: to its rows and columns respectively, or to its columns
+	// and rows if the view is transposed.
+	rows, cols axis
+	// t is whether the view is transposed.
+	t bool
 }
 
-// View creates a rows x cols view of A with its top-left at (r, c). This does
-// not panic if the view exceeds the bounds of A, but out-of-bounds accesses
-// may panic.
+// axis maps zero-based indices along one dimension of a view to zero-based
+// indices of the viewed matrix.
+type axis struct {
+	// If idx is nil, index i maps to off+i*step for i < n.
+	off, step, n int
+	// idx holds the indices explicitly.
+	idx []int
+}
+
+// View creates a rows x cols view of A with its top-left at the one-based
+// index (r, c). Panics if rows or cols is negative or if the view extends
+// outside A.
 func View(A M, r, c, rows, cols int) V {
-	return V{A, r, c, rows, cols}
+	return StridedView(A, r, c, rows, cols, 1, 1)
+}
+
+// StridedView creates a rows x cols view of A selecting every rstep-th row and
+// every cstep-th column, starting at the one-based index (r, c). With a step
+// of k, the view decimates A by k along that dimension. Panics if rows or cols
+// is negative, if either step is not positive, or if the view extends outside
+// A.
+func StridedView(A M, r, c, rows, cols, rstep, cstep int) V {
+	ar, ac := A.Size()
+	if rows < 0 || cols < 0 {
+		panic(fmt.Sprintf("cannot view %dx%d submatrix: size must not be negative", rows, cols))
+	}
+	if rstep <= 0 || cstep <= 0 {
+		panic(fmt.Sprintf("cannot view %dx%d matrix with steps %d, %d: steps must be positive", ar, ac, rstep, cstep))
+	}
+	if r <= 0 || c <= 0 || rows > 0 && r+(rows-1)*rstep > ar || cols > 0 && c+(cols-1)*cstep > ac {
+		panic(fmt.Sprintf("cannot view %dx%d submatrix at (%d,%d) with steps %d, %d of %dx%d matrix: view must be within matrix", rows, cols, r, c, rstep, cstep, ar, ac))
+	}
+	return viewOf(A, axis{off: r - 1, step: rstep, n: rows}, axis{off: c - 1, step: cstep, n: cols}, false)
+}
+
+// PermutedView creates a view of A whose row i is row rows[i-1] of A and whose
+// column j is column cols[j-1] of A, with all indices one-based. A nil slice
+// selects every row or column in order. Indices may be omitted or repeated,
+// in which case the repeated rows or columns of the view refer to the same
+// elements of A. The slices are copied. Panics if any index is outside A.
+func PermutedView(A M, rows, cols []int) V {
+	ar, ac := A.Size()
+	return viewOf(A, permAxis(rows, ar, "row"), permAxis(cols, ac, "column"), false)
+}
+
+// TransposedView creates a view of the transpose of A, so that element (r, c)
+// of the view is element (c, r) of A.
+func TransposedView(A M) V {
+	ar, ac := A.Size()
+	return viewOf(A, axis{step: 1, n: ac}, axis{step: 1, n: ar}, true)
+}
+
+// viewOf creates a view of A with the given axes, which select rows and
+// columns of A, or columns and rows if t is true. If A is itself a view, the
+// result is collapsed into a view of the underlying matrix.
+func viewOf(A M, rows, cols axis, t bool) V {
+	w, ok := A.(V)
+	if !ok {
+		return V{m: A, rows: rows, cols: cols, t: t}
+	}
+	if t {
+		// The new view's rows are the columns of w, and vice versa.
+		return V{m: w.m, rows: w.cols.then(rows), cols: w.rows.then(cols), t: !w.t}
+	}
+	return V{m: w.m, rows: w.rows.then(rows), cols: w.cols.then(cols), t: w.t}
+}
+
+// permAxis creates an axis with explicit one-based indices bounded by n, or
+// the identity on n indices if p is nil.
+func permAxis(p []int, n int, what string) axis {
+	if p == nil {
+		return axis{step: 1, n: n}
+	}
+	idx := make([]int, len(p))
+	for i, k := range p {
+		if k <= 0 || k > n {
+			panic(fmt.Sprintf("%s index %d out of bounds (size %d)", what, k, n))
+		}
+		idx[i] = k - 1
+	}
+	return axis{n: len(p), idx: idx}
 }
 
 // Size returns the size of the view.
 func (v V) Size() (rows, cols int) {
-	return v.rr, v.cc
+	return v.rows.n, v.cols.n
 }
 
-// At proxies to the viewed matrix's At method. This method will panic if the
-// index is outside the view's bounds, and the matrix's method may panic if the
-// adjusted index is outside its bounds.
+// At proxies to the viewed matrix's At method. Panics if the index is outside
+// the view's bounds.
 func (v V) At(r, c int) *big.Int {
 	return v.m.At(v.index(r, c))
 }
 
-// SetAt proxies to the viewed matrix's SetAt method. This method will panic if
-// the index is outside the view's bounds, and the matrix's method may panic if
-// the adjusted index is outside its bounds.
+// SetAt proxies to the viewed matrix's SetAt method. Panics if the index is
+// outside the view's bounds.
 func (v V) SetAt(r, c int, p *big.Int) {
 	r, c = v.index(r, c)
 	v.m.SetAt(r, c, p)
 }
 
-// AddAt proxies to the viewed matrix's AddAt method. This method will panic if
-// the index is outside the view's bounds, and the matrix's method may panic if
-// the adjusted index is outside its bounds.
+// AddAt proxies to the viewed matrix's AddAt method. Panics if the index is
+// outside the view's bounds.
 func (v V) AddAt(r, c int, p *big.Int) *big.Int {
 	r, c = v.index(r, c)
 	return v.m.AddAt(r, c, p)
 }
 
-// MulAt proxies to the viewed matrix's MulAt method. This method will panic if
-// the index is outside the view's bounds, and the matrix's method may panic if
-// the adjusted index is outside its bounds.
+// MulAt proxies to the viewed matrix's MulAt method. Panics if the index is
+// outside the view's bounds.
 func (v V) MulAt(r, c int, p *big.Int) *big.Int {
 	r, c = v.index(r, c)
 	return v.m.MulAt(r, c, p)
 }
 
 // index panics if the given 1-based index is outside the view's bounds and
-// returns the corresponding index into the viewed matrix.
+// returns the corresponding 1-based index into the viewed matrix.
 func (v V) index(r, c int) (int, int) {
-	if r <= 0 || r > v.rr {
-		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r, v.rr, v.cc))
+	if r <= 0 || r > v.rows.n {
+		panic(fmt.Sprintf("row index %d out of bounds (size %dx%d)", r, v.rows.n, v.cols.n))
+	}
+	if c <= 0 || c > v.cols.n {
+		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c, v.rows.n, v.cols.n))
+	}
+	r, c = v.rows.at(r-1)+1, v.cols.at(c-1)+1
+	if v.t {
+		return c, r
+	}
+	return r, c
+}
+
+// at returns the index of the viewed matrix for a zero-based view index.
+func (a axis) at(i int) int {
+	if a.idx != nil {
+		return a.idx[i]
+	}
+	return a.off + i*a.step
+}
+
+// then returns the axis selecting the indices b from the indices of a.
+func (a axis) then(b axis) axis {
+	if a.idx == nil && b.idx == nil {
+		return axis{off: a.off + b.off*a.step, step: a.step * b.step, n: b.n}
+	}
+	idx := make([]int, b.n)
+	for i := range idx {
+		idx[i] = a.at(b.at(i))
+	}
+	return axis{n: b.n, idx: idx}
+}
+
+// contiguous returns whether the axis selects consecutive indices in order.
+func (a axis) contiguous() bool {
+	return a.idx == nil && (a.step == 1 || a.n <= 1)
+}
+
+// inverse returns a function which calls f with each view index mapping to
+// the index k of the viewed matrix.
+func (a axis) inverse() func(k int, f func(i int)) {
+	if a.idx == nil {
+		return func(k int, f func(i int)) {
+			if d := k - a.off; d >= 0 && d%a.step == 0 && d/a.step < a.n {
+				f(d / a.step)
+			}
+		}
+	}
+	inv := make(map[int][]int, len(a.idx))
+	for i, k := range a.idx {
+		inv[k] = append(inv[k], i)
+	}
+	return func(k int, f func(i int)) {
+		for _, i := range inv[k] {
+			f(i)
+		}
+	}
+}
+
+// remapper returns a function which calls f with the zero-based index of each
+// element of the view that refers to the zero-based index (r, c) of the
+// viewed matrix.
+func (v V) remapper() func(r, c int, f func(i, j int)) {
+	ri, ci := v.rows.inverse(), v.cols.inverse()
+	return func(r, c int, f func(i, j int)) {
+		if v.t {
+			r, c = c, r
+		}
+		ri(r, func(i int) {
+			ci(c, func(j int) { f(i, j) })
+		})
+	}
+}
+
+// binary copies the elements of the view of a binary matrix into a new matrix.
+// If the viewed matrix is sparse, its elements are remapped into SM, or LSM if
+// the view is too large for SM; otherwise, the rows of a dense copy are
+// gathered into DM.
+func (v V) binary() M {
+	m := unwrapAdaptive(v.m)
+	rows, cols := v.Size()
+	if _, ok := m.(Z); ok || isSparse(m) {
+		C := NewLSparse(rows, cols)
+		remap := v.remapper()
+		for k := range LSparse(m).v {
+			remap(int(k&0xffffffff), int(k>>32), func(i, j int) { C.v[lkey(i, j)] = 1 })
+		}
+		if rows > 65535 || cols > 65535 {
+			return C
+		}
+		return Sparse(C)
+	}
+	D := Dense(m)
+	C := NewDense(rows, cols)
+	if v.t {
+		for j := 0; j < cols; j++ {
+			src := D.row(v.cols.at(j))
+			for i := 0; i < rows; i++ {
+				if k := v.rows.at(i); src[k/64]&(1<<uint(k%64)) != 0 {
+					C.set(i, j)
+				}
+			}
+		}
+		return C
+	}
+	for i := 0; i < rows; i++ {
+		src, dst := D.row(v.rows.at(i)), C.row(i)
+		if v.cols.contiguous() {
+			copyBits64(dst, src, v.cols.off, cols)
+			continue
+		}
+		for j := 0; j < cols; j++ {
+			if k := v.cols.at(j); src[k/64]&(1<<uint(k%64)) != 0 {
+				dst[j/64] |= 1 << uint(j%64)
+			}
+		}
+	}
+	return C
+}
+
+// poly copies the elements of the view into a new LPSM by remapping the
+// nonzero elements of the viewed matrix.
+func (v V) poly() *LPSM {
+	rows, cols := v.Size()
+	C := NewLPSparse(rows, cols)
+	remap := v.remapper()
+	for k, p := range LPSparse(v.m).v {
+		remap(int(k&0xffffffff), int(k>>32), func(i, j int) { C.v[lkey(i, j)] = new(big.Int).Set(p) })
+	}
+	return C
+}
+
+// mulVec returns the product of the view by a column vector. An untransposed
+// view multiplies the viewed matrix by x scattered to its columns and gathers
+// the result from its rows, so the view is never copied.
+func (v V) mulVec(x *big.Int) *big.Int {
+	if v.t {
+		return MulVec(v.binary(), x)
+	}
+	z := new(big.Int)
+	if v.cols.contiguous() {
+		z.Lsh(x, uint(v.cols.off))
+	} else {
+		forBits(x, func(j int) {
+			k := v.cols.at(j)
+			z.SetBit(z, k, z.Bit(k)^1)
+		})
+	}
+	y := MulVec(v.m, z)
+	if v.rows.contiguous() {
+		y.Rsh(y, uint(v.rows.off))
+		return polyTrunc(y, y, v.rows.n)
+	}
+	r := new(big.Int)
+	for i := 0; i < v.rows.n; i++ {
+		r.SetBit(r, i, y.Bit(v.rows.at(i)))
+	}
+	return r
+}
+
+// copyBits64 sets the low n bits of dst to the n bits of src starting at bit
+// off. Bits of dst above n must be zero already.
+func copyBits64(dst, src []uint64, off, n int) {
+	q, s := off/64, uint(off%64)
+	for i := 0; i < (n+63)/64; i++ {
+		w := src[q+i] >> s
+		if s != 0 && q+i+1 < len(src) {
+			w |= src[q+i+1] << (64 - s)
+		}
+		dst[i] = w
 	}
-	if c <= 0 || c > v.cc {
-		panic(fmt.Sprintf("column index %d out of bounds (size %dx%d)", c, v.rr, v.cc))
+	if n%64 != 0 {
+		dst[(n-1)/64] &= 1<<uint(n%64) - 1
 	}
-	return r - v.r + 1, c - v.c + 1
 }
@@ -0,0 +1,163 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// viewCase is a view along with the function mapping its elements to those of
+// the viewed matrix.
+type viewCase struct {
+	name string
+	v    V
+	f    func(i, j int) (int, int, bool)
+}
+
+// viewCases creates views of A of each kind, including views of views.
+func viewCases(r *rand.Rand, A M) []viewCase {
+	ar, ac := A.Size()
+	id := func(i, j int) (int, int, bool) { return i, j, true }
+	rp, cp := make([]int, ar+2), make([]int, ac/2+1)
+	for i := range rp {
+		rp[i] = r.Intn(ar) + 1
+	}
+	for i := range cp {
+		cp[i] = r.Intn(ac) + 1
+	}
+	sr, sc := ar/3, ac/2
+	strided := StridedView(A, 2, 1, sr, sc, 3, 2)
+	stridedF := func(i, j int) (int, int, bool) { return 2 + (i-1)*3, 1 + (j-1)*2, true }
+	permuted := PermutedView(A, rp, cp)
+	permutedF := func(i, j int) (int, int, bool) { return rp[i-1], cp[j-1], true }
+	transposed := TransposedView(A)
+	transposedF := func(i, j int) (int, int, bool) { return j, i, true }
+	return []viewCase{
+		{"View", View(A, 1, 1, ar, ac), id},
+		{"View offset", View(A, 2, 3, ar-1, ac-2), func(i, j int) (int, int, bool) { return i + 1, j + 2, true }},
+		{"StridedView", strided, stridedF},
+		{"PermutedView", permuted, permutedF},
+		{"PermutedView nil", PermutedView(A, nil, cp), func(i, j int) (int, int, bool) { return i, cp[j-1], true }},
+		{"TransposedView", transposed, transposedF},
+		{"View of StridedView", View(strided, 2, 2, sr-1, sc-1), func(i, j int) (int, int, bool) { return stridedF(i+1, j+1) }},
+		{"StridedView of PermutedView", StridedView(permuted, 1, 2, (ar+3)/2, ac/4, 2, 2), func(i, j int) (int, int, bool) {
+			return permutedF(1+(i-1)*2, 2+(j-1)*2)
+		}},
+		{"TransposedView of StridedView", TransposedView(strided), func(i, j int) (int, int, bool) { return stridedF(j, i) }},
+		{"PermutedView of TransposedView", PermutedView(transposed, []int{3, 1, 3}, []int{ar, 1}), func(i, j int) (int, int, bool) {
+			return transposedF([]int{3, 1, 3}[i-1], []int{ar, 1}[j-1])
+		}},
+		{"StridedView of TransposedView", StridedView(transposed, 2, 1, ac/2, ar/3, 2, 3), func(i, j int) (int, int, bool) {
+			return transposedF(2+(i-1)*2, 1+(j-1)*3)
+		}},
+		{"TransposedView of TransposedView", TransposedView(transposed), id},
+	}
+}
+
+func TestViews(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sz := range [][2]int{{9, 12}, {70, 130}, {130, 200}} {
+		S := randSparse(r, sz[0], sz[1], sz[0]*sz[1]/4)
+		for _, A := range []M{S, Full(S), Dense(S), CompressRows(S)} {
+			for _, c := range viewCases(r, A) {
+				t.Run(c.name, func(t *testing.T) {
+					if _, ok := c.v.m.(V); ok {
+						t.Fatalf("view of %T was not collapsed", A)
+					}
+					checkMap(t, c.v, A, c.f)
+					rows, cols := c.v.Size()
+					if rows == 0 || cols == 0 {
+						return
+					}
+					checkMap(t, Full(c.v), A, c.f)
+					checkMap(t, Sparse(c.v), A, c.f)
+					checkMap(t, Dense(c.v), A, c.f)
+					checkMap(t, LSparse(c.v), A, c.f)
+					checkMulVec(t, r, c.name, c.v)
+					F := Full(c.v)
+					B := randSparse(r, cols, 5, cols)
+					if !sameElements(FMul(c.v, B), fMulNaive(F, B)) || !sameElements(FMul(c.v, Full(B)), fMulNaive(F, B)) {
+						t.Errorf("product of %s of %T is wrong", c.name, A)
+					}
+					C := randSparse(r, 5, rows, rows)
+					if !sameElements(FMul(C, c.v), fMulNaive(C, F)) {
+						t.Errorf("product by %s of %T is wrong", c.name, A)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestViewWrite(t *testing.T) {
+	A := NewFull(6, 8)
+	v := TransposedView(StridedView(A, 2, 3, 3, 3, 2, 2))
+	v.SetAt(1, 2, big.NewInt(1))
+	v.AddAt(3, 3, big.NewInt(1))
+	// Element (1, 2) of the transpose is (2, 1) of the strided view, which is
+	// (4, 3) of A; (3, 3) is (6, 7).
+	want := NewFull(6, 8)
+	want.SetAt(4, 3, big.NewInt(1))
+	want.SetAt(6, 7, big.NewInt(1))
+	if !sameElements(A, want) {
+		t.Errorf("writes through a view reached the wrong elements")
+	}
+	v.MulAt(1, 2, new(big.Int))
+	if A.At(4, 3).Sign() != 0 {
+		t.Errorf("MulAt through a view did not clear the element")
+	}
+	if rows, cols := View(A, 3, 4, 0, 2).Size(); rows != 0 || cols != 2 {
+		t.Errorf("empty view has size %dx%d", rows, cols)
+	}
+}
+
+func TestViewPoly(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	P := randPSM(r, 9, 12, 40, 5)
+	for _, c := range viewCases(r, P) {
+		rows, cols := c.v.Size()
+		if rows == 0 || cols == 0 {
+			continue
+		}
+		L := LPSparse(c.v)
+		for i := 1; i <= rows; i++ {
+			for j := 1; j <= cols; j++ {
+				a, b, _ := c.f(i, j)
+				if L.At(i, j).Cmp(P.At(a, b)) != 0 {
+					t.Fatalf("%s: element (%d,%d) of polynomial view is wrong", c.name, i, j)
+				}
+			}
+		}
+		B := randPFM(r, cols, 3, 4)
+		if !sameElements(PMul(c.v, B), PMul(L, B)) {
+			t.Errorf("%s: polynomial product of view is wrong", c.name)
+		}
+	}
+}
+
+func TestCopyBits64(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	src := make([]uint64, 5)
+	for i := range src {
+		src[i] = r.Uint64()
+	}
+	bit := func(v []uint64, k int) uint64 { return v[k/64] >> uint(k%64) & 1 }
+	for _, off := range []int{0, 1, 13, 63, 64, 65, 127, 130} {
+		for _, n := range []int{1, 7, 63, 64, 65, 128, 190} {
+			if off+n > 64*len(src) {
+				continue
+			}
+			dst := make([]uint64, (n+63)/64)
+			copyBits64(dst, src, off, n)
+			for k := 0; k < len(dst)*64; k++ {
+				want := uint64(0)
+				if k < n {
+					want = bit(src, off+k)
+				}
+				if got := bit(dst, k); got != want {
+					t.Fatalf("copyBits64 at offset %d of %d bits: bit %d is %d, want %d", off, n, k, got, want)
+				}
+			}
+		}
+	}
+}
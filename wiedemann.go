@@ -0,0 +1,339 @@
+package gof2
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// BlockKrylov computes the block Krylov sequence of a square binary matrix A
+// with left projections X and right projections Y. The result is the
+// len(X) x len(Y) polynomial matrix whose element (r, c) has as its
+// coefficient of x^i the inner product of X[r] and A^i Y[c], for i < k.
+// Vectors are as for MulVec. Each column of the sequence is computed on its
+// own goroutine, with up to GOMAXPROCS running at once. Panics if A is not
+// square, if X or Y is empty, or if k is not positive.
+func BlockKrylov(A M, X, Y []*big.Int, k int) *PFM {
+	N, c := A.Size()
+	if N != c {
+		panic(fmt.Sprintf("cannot compute Krylov sequence of %dx%d matrix: matrix must be square", N, c))
+	}
+	if len(X) == 0 || len(Y) == 0 {
+		panic("cannot compute Krylov sequence with empty projections")
+	}
+	if k <= 0 {
+		panic(fmt.Sprintf("cannot compute Krylov sequence of length %d", k))
+	}
+	A = vecSafe(A)
+	m, n := len(X), len(Y)
+	S := NewPFull(m, n)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	ch := make(chan int, n)
+	for j := 0; j < n; j++ {
+		ch <- j
+	}
+	close(ch)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				// Column j of S belongs to this goroutine alone.
+				v := Y[j]
+				for i := 0; i < k; i++ {
+					for r, x := range X {
+						if dotVec(x, v) != 0 {
+							p := S.v[j*m+r]
+							p.SetBit(p, i, 1)
+						}
+					}
+					if i+1 < k {
+						v = MulVec(A, v)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return S
+}
+
+// vecSafe returns A if MulVec handles its type without calling At, which
+// modifies some types of matrices, and a sparse copy of A otherwise. Sum,
+// Product, and untransposed V are rebuilt from safe copies of what they refer
+// to. The result can be used by MulVec on many goroutines at once.
+func vecSafe(A M) M {
+	A = unwrapAdaptive(A)
+	switch X := A.(type) {
+	case *SM, *FM, *LSM, *LFM, *DM, *CSR, *CSC, Comp, Perm, *BM, *TM, Toep, Circ, I, Z, R, S:
+		return A
+	case Sum:
+		t := make([]M, len(X.t))
+		for i, a := range X.t {
+			t[i] = vecSafe(a)
+		}
+		return Sum{r: X.r, c: X.c, t: t}
+	case Product:
+		f := make([]M, len(X.f))
+		for i, a := range X.f {
+			f[i] = vecSafe(a)
+		}
+		return Product{r: X.r, c: X.c, f: f}
+	case V:
+		if X.t {
+			return vecSafe(X.binary())
+		}
+		return V{m: vecSafe(X.m), rows: X.rows, cols: X.cols}
+	}
+	return Sparse(A)
+}
+
+// MatrixBerlekampMassey computes a minimal right generator of the matrix
+// sequence S_0, ..., S_{k-1} given as the m x n polynomial matrix
+// S(x) = sum S_i x^i, e.g. as returned by BlockKrylov. The generator is an
+// n x n polynomial matrix G(x) = sum G_j x^j such that sum_j S_{i+j} G_j = 0
+// for every i where the terms are defined, and whose columns have least
+// possible degrees. When S is the Krylov sequence of a matrix A, the largest
+// invariant factor of G is the minimal polynomial of the sequence.
+//
+// The generator is found from a shifted minimal approximant basis of order
+// k. The sequence determines a generator of column degrees d only if k is at
+// least about d + d*n/m. If it is too short, the result is nil.
+func MatrixBerlekampMassey(S M, k int) *PFM {
+	m, n := S.Size()
+	// Rows [r q] of the approximant basis have q*S^T = r mod x^k. Shifting
+	// r by one makes the shifted degree of each row exceed the degree of r,
+	// so q reversed at that degree is a generator column.
+	f := make([][]*big.Int, m+n)
+	deg := make([]int, m+n)
+	for i := range f {
+		f[i] = make([]*big.Int, m)
+		for j := range f[i] {
+			f[i][j] = new(big.Int)
+		}
+		if i < m {
+			f[i][i].SetInt64(1)
+			deg[i] = 1
+		} else {
+			for j := range f[i] {
+				polyTrunc(f[i][j], S.At(j+1, i-m+1), k)
+			}
+		}
+	}
+	p := eyeRows(m + n)
+	mbasis(p, f, deg, k)
+	// Each row gives a generator column of degree d, the shifted degree of
+	// the row, whose leading coefficient is the constant term of q. Choose
+	// the rows of least degree with independent leading coefficients, so
+	// that the generator is column reduced.
+	type cand struct {
+		row, deg int
+	}
+	cands := make([]cand, len(p))
+	for i, row := range p {
+		cands[i] = cand{i, -1}
+		for j, e := range row {
+			d := PolyDeg(e)
+			if j < m && d >= 0 {
+				d++
+			}
+			if d > cands[i].deg {
+				cands[i].deg = d
+			}
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].deg < cands[j].deg })
+	G := NewPFull(n, n)
+	var basis []*big.Int
+	c := 0
+	for _, x := range cands {
+		lead := new(big.Int)
+		for j := 0; j < n; j++ {
+			lead.SetBit(lead, j, p[x.row][m+j].Bit(0))
+		}
+		if !reduceVec(&basis, lead) {
+			continue
+		}
+		for j := 0; j < n; j++ {
+			G.v[c*n+j] = polyRev(p[x.row][m+j], x.deg)
+		}
+		if c++; c == n {
+			return G
+		}
+	}
+	return nil
+}
+
+// reduceVec reduces x by an echelon basis of vectors with distinct highest
+// bits. If the result is non-zero, it is added to the basis and reduceVec
+// returns true.
+func reduceVec(basis *[]*big.Int, x *big.Int) bool {
+	for _, b := range *basis {
+		if x.Bit(b.BitLen()-1) != 0 {
+			x.Xor(x, b)
+		}
+	}
+	if x.Sign() == 0 {
+		return false
+	}
+	// Keep the basis sorted by decreasing highest bit so that each vector is
+	// reduced only by those above it.
+	i := sort.Search(len(*basis), func(i int) bool { return (*basis)[i].BitLen() < x.BitLen() })
+	*basis = append(*basis, nil)
+	copy((*basis)[i+1:], (*basis)[i:])
+	(*basis)[i] = x
+	return true
+}
+
+// BlockWiedemann holds a minimal generator of a block Krylov sequence of a
+// square binary matrix, from which its minimal and characteristic
+// polynomials and kernel vectors can be recovered. Using n right projections
+// divides the length of the sequence by about n/2 compared to scalar
+// Wiedemann, and the projections are computed in parallel.
+type BlockWiedemann struct {
+	// A is the matrix.
+	A M
+	// X and Y are the left and right projections.
+	X, Y []*big.Int
+	// G is the minimal right generator of the block Krylov sequence.
+	G *PFM
+}
+
+// NewBlockWiedemann computes a minimal generator for the block Krylov
+// sequence of A with m random left projections and n random right
+// projections drawn from rng. If rng is nil, a fixed seed is used. Over GF(2),
+// random projections miss a factor of degree e of the minimal polynomial with
+// probability about 2^-(e min(m, n)), so blocks of 16 or more vectors are
+// advisable. Panics if A is not square, if m or n is not positive, or if no
+// generator is found after maxWiedemannTries sequences of increasing length.
+func NewBlockWiedemann(A M, m, n int, rng *rand.Rand) *BlockWiedemann {
+	N, c := A.Size()
+	if N != c {
+		panic(fmt.Sprintf("cannot run block Wiedemann on %dx%d matrix: matrix must be square", N, c))
+	}
+	if m <= 0 || n <= 0 {
+		panic(fmt.Sprintf("cannot run block Wiedemann with %dx%d projections", m, n))
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(1))
+	}
+	A = vecSafe(A)
+	lim := new(big.Int).Lsh(oneP, uint(N))
+	randVecs := func(n int) []*big.Int {
+		v := make([]*big.Int, n)
+		for i := range v {
+			v[i] = new(big.Int).Rand(rng, lim)
+		}
+		return v
+	}
+	// The generator has degree at most about N/n, and determining it takes
+	// N/m further terms. Unlucky projections over GF(2) may make the
+	// sequence degenerate, so lengthen it and try again if so.
+	extra := 8
+	for try := 0; try < maxWiedemannTries; try++ {
+		X, Y := randVecs(m), randVecs(n)
+		k := (N+m-1)/m + (N+n-1)/n + extra
+		S := BlockKrylov(A, X, Y, k)
+		// A true generator has determinant of degree at most N, which
+		// bounds the sum of its column degrees since it is column reduced.
+		if G := MatrixBerlekampMassey(S, k); G != nil && colDegSum(G) <= N {
+			return &BlockWiedemann{A: A, X: X, Y: Y, G: G}
+		}
+		extra *= 2
+	}
+	panic(fmt.Sprintf("block Wiedemann found no generator for %dx%d matrix with %dx%d projections after %d tries", N, N, m, n, maxWiedemannTries))
+}
+
+// maxWiedemannTries is the number of block Krylov sequences that
+// NewBlockWiedemann generates before giving up. Each is longer than the last
+// by a doubling margin, so the last is 2^(maxWiedemannTries+2) terms longer
+// than the minimum.
+const maxWiedemannTries = 10
+
+// colDegSum returns the sum of the column degrees of a polynomial matrix.
+func colDegSum(G *PFM) int {
+	s := 0
+	for c := 0; c < int(G.c); c++ {
+		d := 0
+		for _, p := range G.v[c*int(G.r) : (c+1)*int(G.r)] {
+			if e := PolyDeg(p); e > d {
+				d = e
+			}
+		}
+		s += d
+	}
+	return s
+}
+
+// MinPoly returns the largest invariant factor of the generator. With high
+// probability, this is the minimal polynomial of A.
+func (w *BlockWiedemann) MinPoly() *big.Int {
+	d, _, _ := Smith(w.G, false)
+	return d[len(d)-1]
+}
+
+// CharPoly returns the determinant of the generator, which is the product of
+// the n largest invariant factors of A with high probability. It is the
+// characteristic polynomial of A if its degree equals the size of A, which
+// is certain when A has at most n non-trivial invariant factors and the
+// projections are not unlucky.
+func (w *BlockWiedemann) CharPoly() *big.Int {
+	return Det(w.G)
+}
+
+// Kernel returns linearly independent vectors x such that A x = 0, found
+// from the generator columns. If A is singular, the result is non-empty with
+// high probability, but it need not span the kernel.
+func (w *BlockWiedemann) Kernel() []*big.Int {
+	n := len(w.Y)
+	var r, basis []*big.Int
+	for c := 0; c < n; c++ {
+		// The column is x^t h(x) with sum_j A^j Y h_j in the kernel of A^t.
+		col := w.G.v[c*n : c*n+n]
+		t, d := -1, -1
+		for _, p := range col {
+			if p.Sign() == 0 {
+				continue
+			}
+			if z := int(p.TrailingZeroBits()); t < 0 || z < t {
+				t = z
+			}
+			if e := PolyDeg(p); e > d {
+				d = e
+			}
+		}
+		if t < 0 {
+			continue
+		}
+		u := new(big.Int)
+		for j := d; j >= t; j-- {
+			u = MulVec(w.A, u)
+			for i, p := range col {
+				if p.Bit(j) != 0 {
+					u.Xor(u, w.Y[i])
+				}
+			}
+		}
+		for i := 0; i < t && u.Sign() != 0; i++ {
+			v := MulVec(w.A, u)
+			if v.Sign() == 0 {
+				break
+			}
+			u = v
+		}
+		if u.Sign() == 0 || MulVec(w.A, u).Sign() != 0 {
+			continue
+		}
+		if reduceVec(&basis, new(big.Int).Set(u)) {
+			r = append(r, u)
+		}
+	}
+	return r
+}
@@ -0,0 +1,94 @@
+package gof2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestBlockKrylov(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const n, k = 30, 7
+	A := randSparse(r, n, n, 3*n)
+	X := []*big.Int{randBits(r, n), randBits(r, n), randBits(r, n)}
+	Y := []*big.Int{randBits(r, n), randBits(r, n)}
+	S := BlockKrylov(A, X, Y, k)
+	for c, y := range Y {
+		v := y
+		for i := 0; i < k; i++ {
+			for rw, x := range X {
+				if got, want := S.At(rw+1, c+1).Bit(i), dotVec(x, v); got != want {
+					t.Errorf("coefficient %d of element (%d,%d) is %d, want %d", i, rw+1, c+1, got, want)
+				}
+			}
+			v = mulVecNaive(A, v)
+		}
+	}
+}
+
+// blockDiag creates a sparse block diagonal matrix from square blocks.
+func blockDiag(blocks ...M) *SM {
+	n := 0
+	for _, b := range blocks {
+		k, _ := b.Size()
+		n += k
+	}
+	A := NewSparse(n, n)
+	o := 0
+	for _, b := range blocks {
+		k, _ := b.Size()
+		for i := 1; i <= k; i++ {
+			for j := 1; j <= k; j++ {
+				A.SetAt(o+i, o+j, b.At(i, j))
+			}
+		}
+		o += k
+	}
+	return A
+}
+
+func TestBlockWiedemann(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, n := range []int{1, 5, 40, 100} {
+		f := randPoly(r, n)
+		f.SetBit(f, 0, 1)
+		w := NewBlockWiedemann(Companion(f), 8, 8, r)
+		if got := w.MinPoly(); got.Cmp(f) != 0 {
+			t.Errorf("minimal polynomial of companion of %v is %v", f, got)
+		}
+		if got := w.CharPoly(); got.Cmp(f) != 0 {
+			t.Errorf("characteristic polynomial of companion of %v is %v", f, got)
+		}
+	}
+	// A repeated block keeps the minimal polynomial but squares the
+	// characteristic polynomial.
+	f := big.NewInt(0x11b)
+	F := Companion(f)
+	w := NewBlockWiedemann(blockDiag(F, F), 8, 8, r)
+	if got := w.MinPoly(); got.Cmp(f) != 0 {
+		t.Errorf("minimal polynomial of doubled companion of %v is %v", f, got)
+	}
+	if got, want := w.CharPoly(), PolyMul(new(big.Int), f, f); got.Cmp(want) != 0 {
+		t.Errorf("characteristic polynomial of doubled companion of %v is %v, want %v", f, got, want)
+	}
+}
+
+func TestBlockWiedemannKernel(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, n := range []int{10, 50, 120} {
+		A := randSparse(r, n, n, 3*n)
+		// Make the matrix singular by duplicating a column.
+		for i := 1; i <= n; i++ {
+			A.SetAt(i, n, A.At(i, 1))
+		}
+		ker := NewBlockWiedemann(A, 8, 8, r).Kernel()
+		if len(ker) == 0 {
+			t.Errorf("no kernel vectors for singular %dx%d matrix", n, n)
+		}
+		for _, x := range ker {
+			if x.Sign() == 0 || MulVec(A, x).Sign() != 0 {
+				t.Errorf("%v is not a non-zero kernel vector of %dx%d matrix", x, n, n)
+			}
+		}
+	}
+}